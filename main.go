@@ -5,49 +5,421 @@
 // 特定占位:
 //   - `<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->`              仪表盘表格（Markdown 格式）
 //   - `<!-- md:PubDashboard-total begin --><!-- md:PubDashboard-total end -->`  Package 数量
+//   - `<!-- md:PubDashboard-downloads begin --><!-- md:PubDashboard-downloads end -->`  30 天下载量总和
+//   - `<!-- md:PubDashboard-likes begin --><!-- md:PubDashboard-likes end -->`  likes 总和
+//   - `<!-- md:PubDashboard-stars begin --><!-- md:PubDashboard-stars end -->`  GitHub stars 总和
+//   - `<!-- md:PubDashboard-points begin --><!-- md:PubDashboard-points end -->`  pub points 平均值
+//   - `<!-- md:PubDashboard-platforms begin --><!-- md:PubDashboard-platforms end -->`  每个平台的 package 数量
+//   - `<!-- md:PubDashboard-contributors begin --><!-- md:PubDashboard-contributors end -->`  不重复的贡献者数量
+//   - `<!-- md:PubDashboard-leaderboard begin --><!-- md:PubDashboard-leaderboard end -->`  贡献者排行（参与 package 最多的贡献者）
+//   - `<!-- md:PubDashboard-releases begin --><!-- md:PubDashboard-releases end -->`  pub 版本缺少对应 git tag / GitHub release 的 package
+//   - `<!-- md:PubDashboard-attention begin --><!-- md:PubDashboard-attention end -->`  健康度最低、需要关注的 package 及原因
 //
 // 使用:
 //   - `go run main.go -githubToken xxx -filename xxx -publisherList xxx -packageList xxx -sortField xxx -sortMode xxx`
+//   - `go run main.go -githubToken xxx -publisherList xxx -targets xxx -dir xxx -check`
+//   - 其余参数见下方列表，例如：`-render native -health -snapshot xxx -notify xxx`
 //
 // 参数:
 //   - [githubToken]    拥有 repo 权限的 Github 令牌
 //   - [filename]       需要更新的 Markdown 文件，例如："README.md" "test/test.md"
+//   - [targets]        多个输出目标（每行一个："文件 key=value ..."，共享同一次数据抓取），设置后忽略 filename，
+//     key 可选：sortField | sortMode | groupByRepo | issueLabel | activity | contributorsLayout | contributorsCount | contributorsSize | leaderboardLayout | leaderboardCount | health | attentionCount | attentionThreshold | locale | render | markers | filter，例如："README_CN.md sortField=pubDownloads markers=total,downloads"
+//   - [dir]            相对路径（filename、targets）的基准目录，默认当前目录
 //   - [publisherList]  Publisher 名称列表 (`,`逗号分割) ，例如："aa,bb,cc"
 //   - [packageList]    Package 名称列表 (`,`逗号分割)，例如："aa,bb,cc"
-//   - [sortField]      排序字段 可选：name(default) | published | pubLikes | pubDownloads | githubStars
+//   - [sortField]      排序字段 可选：name(default) | published | pubLikes | pubDownloads | githubStars | githubIssues | githubPullRequests | health
 //   - [sortMode]       排序方式 可选：asc(default) | desc
+//   - [filter]         package 过滤条件（`,`逗号分割，同时满足），例如："githubPullRequests>20,pubPoints>=140"
+//   - [groupByRepo]    同一 Github 仓库的 package 合并为一组展示（适用于 monorepo） 可选：false(default) | true
+//   - [activity]       展示仓库活跃度（已归档、最近推送天数、默认分支 CI 状态） 可选：false(default) | true
+//   - [contributorsLayout]  贡献者展示方式 可选：stacked(default) | inline | names | none（不展示贡献者列）
+//   - [contributorsCount]   展示的贡献者数量，默认：3
+//   - [contributorsSize]    贡献者头像宽度（像素），默认：36
+//   - [leaderboardLayout]   贡献者排行（PubDashboard-leaderboard）展示方式 可选：table(default) | avatars
+//   - [leaderboardCount]    贡献者排行展示数量，默认：10
+//   - [health]         展示健康度列（pub points、最新发布距今天数、open issues / Pull requests、已归档、停止维护、许可证） 可选：false(default) | true
+//   - [healthWeights]  健康度扣分项权重（覆盖默认值，权重为 0 时不计入），默认："points=30,published=20,issues=10,pullRequests=10,archived=20,discontinued=30,license=10"
+//   - [attentionCount]     需要关注（PubDashboard-attention）的 package 展示数量，默认：5
+//   - [attentionThreshold] 健康度低于该值的 package 需要关注（0 为不展示），默认：80
+//   - [contributorsAnon]    贡献者总数包含匿名贡献者（未关联 GitHub 账号的提交邮箱） 可选：false(default) | true
+//   - [contributorsExclude] 排除的贡献者（`,`逗号分割，支持通配符 `*`），例如："renovate-bot,*-bot"
+//   - [locale]         表格语言（文案、数字与日期格式） 可选：en(default) | zh-CN
+//   - [render]         渲染方式 可选：badge(default，Shields 徽章) | native（直接展示已获取的数据） | svg（生成 SVG 徽章文件）
+//   - [assetsDir]      svg 渲染时徽章的输出目录（相对于 dir），默认："assets/pub-dashboard"
+//   - [issueLabel]     monorepo 子目录中 package 的 Issues / Pull_requests 按 label 过滤（`{name}` 为 package 名称），例如："p: {name}"
+//   - [rateLimitWait]  命中 pub.dev / GitHub 限流后最长等待时长，超出则失败，例如："1m" "30s"
+//   - [hostLimits]     按 host 限流（host=并发数:每秒请求数，`,`逗号分割，`*` 为其余 host），默认："pub.dev=8:10,api.github.com=6:10,*=4:5"
+//   - [cacheDir]       HTTP 磁盘缓存目录，为空时不缓存，例如：".cache/pub-dashboard"
+//   - [pubURL]         pub 仓库地址，默认：环境变量 PUB_HOSTED_URL 或 "https://pub.dev"
+//   - [githubAPIURL]   GitHub API 地址，默认：环境变量 GITHUB_API_URL 或 "https://api.github.com"，例如："https://ghe.example.com/api/v3"
+//   - [githubURL]      GitHub 网页地址，默认：环境变量 GITHUB_SERVER_URL 或由 githubAPIURL 推导
+//   - [hostedPackageList] 自托管 pub 仓库（Hosted Pub Repository 规范）的 package 列表（名称不能与其他来源的 package 重复），例如："https://pub.example.com=aa,bb;https://pub2.example.com=cc"
+//   - [pubTokens]      dart pub token 凭据文件（pub-tokens.json），默认与 `dart pub token` 一致
+//   - [dry-run]        仅输出变化的 unified diff，不写入文件
+//   - [check]          仅检查文件是否过期（过期时退出码为 1），不写入文件
+//   - [strict]         区块标记的警告（未知、格式错误、重复、缺失表格）视为错误
+//   - [strictVersions] pub 最新版本缺少对应的 git tag（如 v1.2.3、foo-v1.2.3）时失败
+//   - [snapshot]       运行快照文件（相对于 dir），与上一次运行对比生成告警（pub points 下降、新的安全公告、仓库被归档、下载量下降），告警同时写入 GitHub Actions job summary，例如：".pub-dashboard/snapshot.json"
+//   - [alertsFile]     告警 JSON 文件（需设置 snapshot，相对于 dir），例如："alerts.json"
+//   - [alertsWebhook]  告警 POST 地址（需设置 snapshot），请求体为 `{"alerts": [...]}`
+//   - [alertsDownloadsDrop] 30 天下载量下降超过该百分比时告警（0 为不告警），默认：50
+//   - [notify]         更新后（dry-run / check 模式下不发送）POST 运行结果（变化摘要、新增/移除的 package、失败信息）的地址（空白或换行分割的 "格式=地址"，格式：slack | discord | teams | custom），例如："slack=https://hooks.slack.com/services/xxx"
+//   - [notifyTemplate] custom 通知的 JSON 模板文件（text/template，参数见 [RunReport]，`json` 函数输出 JSON 值），例如：`{"text": {{json .Summary}}}`
+//   - [notifyOn]       发送通知的时机 可选：changes(default，文件有变化、package 有增减或有失败时) | always
+//   - [cacheTTL]       按接口类型（package | score | search | github | other）的缓存新鲜期（覆盖默认值），默认："package=6h,score=1h,search=1h,github=0s"
 package main
 
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html"
 	"io"
+	"maps"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 )
 
 const (
-	// maxConcurrency 是 package 级别的并发抓取上限。
-	// 需要按 pub.dev 和 GitHub 限流取保守值。
-	maxConcurrency = 8
+	// maxPackageConcurrency 是 package / publisher 级别的并发抓取上限，仅限制 goroutine 数量。
+	// 实际请求的并发数与速率由 HTTP 层按 host 限流（见 [defaultHostLimits]）。
+	maxPackageConcurrency = 16
+	// maxPublisherPages 是每个 publisher 最多查询的页数（pub.dev 搜索最多 10 页），防止 next 异常时无限翻页。
+	maxPublisherPages = 10
+	// publisherPageConcurrency 是单个 publisher 每批并发查询的页数，某批中出现无 next 的页后不再查询下一批。
+	publisherPageConcurrency = 3
 	// maxAttempts 是单个 HTTP 请求的最大尝试次数（含首次）。
 	maxAttempts = 3
 	// httpTimeout 是单个 HTTP 请求的超时时间，防止请求挂起拖跨整个 Action。
 	httpTimeout = 30 * time.Second
 	// retryBaseDelay 是重试的基础退避时长（指数增长）。
 	retryBaseDelay = 500 * time.Millisecond
+	// defaultRateLimitMaxWait 是命中限流后默认愿意等待的最长时长。
+	defaultRateLimitMaxWait = time.Minute
+	// defaultHostLimits 是默认的按 host 限流（host=并发数:每秒请求数），`*` 为其余 host。
+	// pub.dev 与 GitHub 的限流差异较大，需分别取保守值。
+	defaultHostLimits = "pub.dev=8:10,api.github.com=6:10,*=4:5"
+	// defaultCacheTTL 是磁盘缓存默认的按接口类型新鲜期（期内直接使用缓存，不发起请求）。
+	// package 元数据很少变化，评分每天变化；GitHub 的 304 响应不计入限流，总是重新验证。
+	defaultCacheTTL = "package=6h,score=1h,search=1h,github=0s"
+	// defaultPubURL 是默认的 pub 仓库地址（API 与网页）。
+	defaultPubURL = "https://pub.dev"
+	// defaultGithubAPIURL 是默认的 GitHub API 地址。
+	defaultGithubAPIURL = "https://api.github.com"
+	// defaultGithubURL 是默认的 GitHub 网页地址。
+	defaultGithubURL = "https://github.com"
 )
 
+// errRateLimited 表示请求被 pub.dev / GitHub 限流且无法在等待上限内恢复。
+var errRateLimited = errors.New("rate limit exceeded")
+
+// HTTPClient 是共享的 HTTP Client 及其请求策略
+type HTTPClient struct {
+	*http.Client
+	// 命中限流（Retry-After / X-RateLimit-Reset）后愿意等待的最长时长，超出则直接返回 [errRateLimited]
+	RateLimitMaxWait time.Duration
+
+	// 磁盘缓存（nil 表示不缓存）
+	Cache *HTTPCache
+
+	hostLimits map[string]HostLimit    // 按 host 的限流配置，`*` 为其余 host
+	limiters   map[string]*hostLimiter // 按 host 懒创建的限流器
+	mutex      sync.Mutex
+}
+
+// 磁盘 HTTP 缓存
+//
+// 按 URL 与凭据（Authorization 请求头的哈希）存储 200 响应及其 ETag / Last-Modified，
+// 不同 token 或匿名请求互不复用缓存：
+// 新鲜期内直接使用缓存；过期后发起条件请求（If-None-Match / If-Modified-Since），304 时复用缓存。
+type HTTPCache struct {
+	Dir string                   // 缓存目录
+	TTL map[string]time.Duration // 按接口类型（见 [cacheEndpointType]）的新鲜期
+}
+
+// 单个缓存条目
+type httpCacheEntry struct {
+	URL          string    `json:"url"`
+	Credential   string    `json:"credential,omitempty"` // Authorization 请求头的哈希（匿名请求为空）
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Link         string    `json:"link,omitempty"` // 分页信息（用于统计总数）
+	StoredAt     time.Time `json:"storedAt"`
+	Body         []byte    `json:"body"`
+}
+
+// HTTP 响应
+type httpResponse struct {
+	Body   []byte
+	Status int
+	Header http.Header
+}
+
+// 单个 host 的限流配置
+type HostLimit struct {
+	Concurrency       int     // 最大并发请求数
+	RequestsPerSecond float64 // 每秒最多发起的请求数（0 表示不限速）
+}
+
+// 单个 host 的限流器：限制并发数，并按固定间隔平滑发起请求
+type hostLimiter struct {
+	semaphore chan struct{}
+	interval  time.Duration // 两次请求之间的最小间隔（0 表示不限速）
+	mutex     sync.Mutex
+	next      time.Time // 下一次允许发起请求的时间
+}
+
+// 服务地址，支持 pub 镜像（PUB_HOSTED_URL）、GitHub Enterprise Server 及测试用的本地服务
+type Endpoints struct {
+	PubURL       string // pub 仓库地址（API 与网页），例如：https://pub.dev
+	GithubAPIURL string // GitHub API 地址，例如：https://api.github.com、https://ghe.example.com/api/v3
+	GithubURL    string // GitHub 网页地址，例如：https://github.com、https://ghe.example.com
+}
+
+// 抓取选项
+type FetchOptions struct {
+	ContributorsAnon bool // 贡献者总数是否包含匿名贡献者（未关联 GitHub 账号的提交邮箱）
+	Advisories       bool // 是否获取 pub 安全公告（用于运行间的告警对比）
+	Activity         bool // 是否获取默认分支的 CI 状态（仅在某个输出目标开启 activity 时需要）
+	OpenCounts       bool // 是否获取 open Issues / Pull requests 数量（见 [targetFetchOptions]）
+	Releases         bool // 是否获取 GitHub tags 与最新 release（见 [targetFetchOptions]）
+
+	IssueLabels []string // monorepo 子目录中 package 的 label 模板（见 issueLabel），按 label 获取 open Issues / Pull requests 数量
+
+	repos *githubRepoCache // 按仓库缓存的 Github 信息（为空时不缓存）
+}
+
+// 单个 Github 仓库的信息（同一仓库的 package 共享）
+type githubRepoInfo struct {
+	BaseInfo          GithubBaseInfo
+	Contributors      []GithubContributorsInfo
+	ContributorsTotal int
+	OpenCounts        GithubOpenCounts
+	CIStatus          string
+	Tags              []string // 仓库不存在时为 nil
+	TagsTruncated     bool     // tags 超过一页（未匹配时需按名称逐个查询）
+	ReleaseTag        string
+}
+
+// 按仓库缓存的 Github 信息，monorepo 中的 package 只请求一次
+type githubRepoCache struct {
+	mu    sync.Mutex
+	repos map[string]*githubRepoEntry
+}
+
+type githubRepoEntry struct {
+	once sync.Once
+	info githubRepoInfo
+	err  error
+}
+
+// 创建服务地址
+//
+// 参数为空时依次使用环境变量与默认值；[githubURL] 为空时由 [githubAPIURL] 推导
+// （api.github.com -> github.com，https://host/api/v3 -> https://host）。
+//
+// 参数:
+//   - [pubURL]       pub 仓库地址（PUB_HOSTED_URL）
+//   - [githubAPIURL] GitHub API 地址（GITHUB_API_URL）
+//   - [githubURL]    GitHub 网页地址（GITHUB_SERVER_URL）
+func newEndpoints(pubURL string, githubAPIURL string, githubURL string) Endpoints {
+	firstNonEmpty := func(values ...string) string {
+		for _, value := range values {
+			if value = strings.TrimRight(strings.TrimSpace(value), "/"); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	endpoints := Endpoints{
+		PubURL:       firstNonEmpty(pubURL, os.Getenv("PUB_HOSTED_URL"), defaultPubURL),
+		GithubAPIURL: firstNonEmpty(githubAPIURL, os.Getenv("GITHUB_API_URL"), defaultGithubAPIURL),
+	}
+	derivedGithubURL := defaultGithubURL
+	if endpoints.GithubAPIURL != defaultGithubAPIURL {
+		derivedGithubURL = strings.TrimSuffix(endpoints.GithubAPIURL, "/api/v3")
+	}
+	if githubAPIURL != "" {
+		// 显式指定 API 地址时，优先使用与之匹配的网页地址
+		endpoints.GithubURL = firstNonEmpty(githubURL, derivedGithubURL)
+	} else {
+		endpoints.GithubURL = firstNonEmpty(githubURL, os.Getenv("GITHUB_SERVER_URL"), derivedGithubURL)
+	}
+	return endpoints
+}
+
+// 文件更新模式
+type UpdateMode int
+
+const (
+	UpdateModeWrite  UpdateMode = iota // 写入文件
+	UpdateModeDryRun                   // 仅输出 diff，不写入
+	UpdateModeCheck                    // 仅检查是否过期，不写入
+)
+
+func (m UpdateMode) String() string {
+	switch m {
+	case UpdateModeDryRun:
+		return "dry-run"
+	case UpdateModeCheck:
+		return "check"
+	}
+	return "write"
+}
+
+// 区块标记名称
+const (
+	markerTable        = "PubDashboard"              // 表格：<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->
+	markerTotal        = "PubDashboard-total"        // 总数：<!-- md:PubDashboard-total begin --><!-- md:PubDashboard-total end -->
+	markerDownloads    = "PubDashboard-downloads"    // 30 天下载量总和
+	markerLikes        = "PubDashboard-likes"        // likes 总和
+	markerStars        = "PubDashboard-stars"        // GitHub stars 总和
+	markerPoints       = "PubDashboard-points"       // pub points 平均值
+	markerPlatforms    = "PubDashboard-platforms"    // 每个平台的 package 数量
+	markerContributors = "PubDashboard-contributors" // 不重复的贡献者数量
+	markerLeaderboard  = "PubDashboard-leaderboard"  // 贡献者排行（参与 package 最多的贡献者）
+	markerReleases     = "PubDashboard-releases"     // pub 版本缺少对应 git tag / GitHub release 的 package
+	markerAttention    = "PubDashboard-attention"    // 健康度最低、需要关注的 package 及原因
+)
+
+// 支持的区块标记名称
+var markerNames = []string{markerTable, markerTotal, markerDownloads, markerLikes, markerStars, markerPoints, markerPlatforms, markerContributors, markerLeaderboard, markerReleases, markerAttention}
+
+// Markdown 文件中的区块（成对的 begin / end 标记）
+type markdownBlock struct {
+	Name  string // 区块名称
+	Line  int    // begin 标记所在行
+	Start int    // 区块内容起始位置（begin 标记之后）
+	End   int    // 区块内容结束位置（end 标记之前）
+}
+
+// 区块标记诊断信息
+type MarkerDiagnostic struct {
+	Line    int    // 所在行，0 表示整个文件
+	Message string // 诊断信息
+	Warning bool   // 是否为警告（strict 模式下视为错误）
+}
+
+// Markdown 文件更新选项
+type UpdateOptions struct {
+	Mode   UpdateMode // 更新模式
+	Strict bool       // 标记警告（未知、重复、缺失表格）视为错误
+}
+
+// Markdown 文件中待更新的区块内容
+type MarkdownBlocks struct {
+	Table   string       // <!-- md:PubDashboard begin --><!-- md:PubDashboard end -->
+	Total   int          // <!-- md:PubDashboard-total begin --><!-- md:PubDashboard-total end -->
+	Stats   PackageStats // 汇总统计区块
+	Markers []string     // 仅更新的区块名称，为空时更新全部
+	Locale  string       // 语言（页脚与汇总区块的格式），为空时为 en
+
+	Leaderboard LeaderboardOptions // 贡献者排行区块选项
+	Attention   AttentionOptions   // 需要关注区块选项
+}
+
+// 需要关注区块选项
+type AttentionOptions struct {
+	Count     int  // 展示的 package 数量（为 0 时为 [defaultAttentionCount]）
+	Threshold *int // 健康度低于该值的 package 才展示（为 nil 时为 [defaultAttentionThreshold]，为 0 时不展示任何 package）
+}
+
+// 需要关注区块默认值
+const (
+	defaultAttentionCount     = 5
+	defaultAttentionThreshold = 80
+)
+
+// 贡献者排行区块选项
+type LeaderboardOptions struct {
+	Layout string // 展示方式：table（默认）| avatars
+	Count  int    // 展示的贡献者数量（为 0 时为 [defaultLeaderboardCount]）
+}
+
+// 贡献者排行展示方式
+const (
+	leaderboardTable   = "table"   // 排名表格
+	leaderboardAvatars = "avatars" // 头像墙
+)
+
+// 支持的贡献者排行展示方式
+var leaderboardLayouts = []string{leaderboardTable, leaderboardAvatars}
+
+// 贡献者排行默认展示数量
+const defaultLeaderboardCount = 10
+
+// 输出目标（共享同一次数据抓取）
+type Target struct {
+	Filename string            // 更新的文件
+	SortMode string            // 排序方式 asc | desc
+	Table    TableOptions      // 表格选项（排序字段、分组等）
+	Markers  []string          // 仅更新的区块名称，为空时更新全部
+	Filter   []FilterCondition // package 过滤条件，为空时不过滤
+
+	Leaderboard LeaderboardOptions // 贡献者排行区块选项
+	Attention   AttentionOptions   // 需要关注区块选项
+}
+
+// package 汇总统计
+type PackageStats struct {
+	Downloads    int               // 30 天下载量总和
+	Likes        int               // likes 总和
+	Stars        int               // GitHub stars 总和（同一仓库只计一次）
+	Points       int               // pub points 平均值（四舍五入，仅统计有评分的 package）
+	Platforms    map[string]int    // 每个平台的 package 数量
+	Contributors int               // 不重复的贡献者数量（非 Bot，每个仓库最多统计 100 位）
+	Releases     []PackageInfo     // pub 版本缺少对应 git tag / GitHub release 的 package
+	Leaderboard  []ContributorRank // 贡献者排行（按参与的 package 数量降序）
+	Attention    []PackageInfo     // 存在健康问题的 package（按健康度升序）
+}
+
+// 贡献者排行中的贡献者
+type ContributorRank struct {
+	GithubContributorsInfo
+	Packages      int // 参与的 package 数量
+	Contributions int // 提交数（同一仓库只计一次）
+}
+
+// package 来源（实现 Hosted Pub Repository 规范的 pub 仓库）
+type PackageSource struct {
+	URL    string // pub 仓库地址
+	Token  string // Bearer Token（为空时匿名访问）
+	Hosted bool   // 是否为自托管仓库（非 pub.dev 及其镜像：无评分数据时降级，不使用 Shields 徽章）
+}
+
+// 待抓取的 package
+type PackageRef struct {
+	Name   string
+	Source PackageSource
+}
+
+// dart pub token 凭据文件（pub-tokens.json）
+type PubTokens struct {
+	HostedPubRepositories []struct {
+		URL   string `json:"url"`
+		Token string `json:"token"`
+		Env   string `json:"env"` // 从环境变量读取 Token（dart pub token add --env-var）
+	} `json:"hostedPubRepositories"`
+}
+
 // 主 MarkdownTable 用于存储每个 package 在 Markdown 表格中的展示信息
 type MarkdownTable struct {
 	Name                   string
@@ -63,12 +435,17 @@ type MarkdownTable struct {
 	Issues                 string
 	PullRequests           string
 	Contributors           string
+	Activity               string // 仓库活跃度（未开启时为空）
+	Release                string // pub 版本与 GitHub tag / release 不一致的原因（一致时为空）
+	Health                 string // 健康度（未开启时为空）
 }
 
 // 主 Package 信息，聚合 package 所有相关的数据
 type PackageInfo struct {
 	Code                   int // 0: error 1：success
 	Name                   string
+	PubURL                 string // package 所在 pub 仓库地址（为空时为 [defaultPubURL]）
+	Hosted                 bool   // 是否来自自托管 pub 仓库
 	Version                string
 	Description            string
 	Homepage               string
@@ -77,9 +454,27 @@ type PackageInfo struct {
 	Published              string
 	GithubUser             string
 	GithubRepo             string
+	GithubRef              string // monorepo 中 package 所在的分支/标签，如 main
+	GithubPath             string // monorepo 中 package 所在的子目录，如 packages/foo
 	GithubBaseInfo         GithubBaseInfo
+	GithubLabelCounts      map[string]GithubOpenCounts // 按 label 的 open Issues / Pull requests 数量（见 [FetchOptions.IssueLabels]）
 	GithubContributorsInfo []GithubContributorsInfo
+	GithubOpenIssues       int    // open issues 数量（不含 Pull requests）
+	GithubOpenPullRequests int    // open Pull requests 数量
+	GithubCIStatus         string // 默认分支最新提交的 CI 状态 success | failure | pending，未知时为空
+	GithubReleaseChecked   bool   // 是否已获取 GitHub release 与 tags
+	GithubReleaseTag       string // 最新 GitHub release 的 tag（无 release 时为空）
+	GithubVersionTag       string // pub 最新版本对应的 git tag（未找到时为空）
+	Discontinued           bool   // pub 上是否已标记为停止维护
 	ScoreInfo              PackageScoreInfo
+	Health                 PackageHealth // 健康度（见 [computeHealth]）
+	Advisories             []string      // pub 安全公告 ID（如 GHSA-xxxx，未获取时为空）
+}
+
+// Github open Issues / Pull requests 数量
+type GithubOpenCounts struct {
+	Issues       int // open Issues 数量（不含 Pull requests）
+	PullRequests int // open Pull requests 数量
 }
 
 // 每个 package 对应 Github 仓库的基础信息
@@ -90,9 +485,28 @@ type GithubBaseInfo struct {
 	License         struct {
 		Name string `json:"name"`
 	} `json:"license"`
+	Archived          bool     `json:"archived"`
+	PushedAt          string   `json:"pushed_at"` // 最近推送时间（RFC3339）
+	DefaultBranch     string   `json:"default_branch"`
+	Topics            []string `json:"topics"`
 	ContributorsTotal int
 }
 
+// CI 状态
+const (
+	ciSuccess = "success"
+	ciFailure = "failure"
+	ciPending = "pending"
+)
+
+// Github 提交的 check runs
+type GithubCheckRuns struct {
+	CheckRuns []struct {
+		Status     string `json:"status"`     // queued | in_progress | completed ...
+		Conclusion string `json:"conclusion"` // success | failure | neutral | skipped ...
+	} `json:"check_runs"`
+}
+
 // 每个 package 对应 Github 仓库的贡献者基础信息
 type GithubContributorsInfo struct {
 	Login     string `json:"login"`
@@ -100,12 +514,15 @@ type GithubContributorsInfo struct {
 	AvatarUrl string `json:"avatar_url"`
 	HtmlUrl   string `json:"html_url"`
 	Type      string `json:"type"`
+	// 提交数
+	Contributions int `json:"contributions"`
 }
 
 // Pub.dev package 基础信息
 type PackageBaseInfo struct {
-	Name   string `json:"name"`
-	Latest struct {
+	Name           string `json:"name"`
+	IsDiscontinued bool   `json:"isDiscontinued"`
+	Latest         struct {
 		Pubspec struct {
 			Version      string `json:"version"`
 			Description  string `json:"description"`
@@ -117,6 +534,13 @@ type PackageBaseInfo struct {
 	} `json:"latest"`
 }
 
+// Pub.dev package 安全公告
+type PackageAdvisoriesInfo struct {
+	Advisories []struct {
+		Id string `json:"id"`
+	} `json:"advisories"`
+}
+
 // Pub.dev package 评分相关信息
 type PackageScoreInfo struct {
 	GrantedPoints       float64  `json:"grantedPoints"`
@@ -136,42 +560,504 @@ type PublisherInfo struct {
 	Next string `json:"next"`
 }
 
+// 命令行参数（说明见文件头部的参数列表）
+type CommandFlags struct {
+	GithubToken string
+	Filename    string
+	TargetList  string
+	Dir         string
+
+	// package 来源
+	PublisherList     string
+	PackageList       string
+	HostedPackageList string
+	PubTokens         string
+	PubURL            string
+	GithubAPIURL      string
+	GithubURL         string
+
+	// 表格
+	SortField           string
+	SortMode            string
+	FilterList          string
+	GroupByRepo         bool
+	Activity            bool
+	IssueLabel          string
+	Locale              string
+	Render              string
+	AssetsDir           string
+	ContributorsLayout  string
+	ContributorsCount   int
+	ContributorsSize    int
+	ContributorsAnon    bool
+	ContributorsExclude string
+	LeaderboardLayout   string
+	LeaderboardCount    int
+	Health              bool
+	HealthWeights       string
+	AttentionCount      int
+	AttentionThreshold  int
+
+	// 请求
+	RateLimitWait time.Duration
+	HostLimits    string
+	CacheDir      string
+	CacheTTL      string
+
+	// 更新模式与检查
+	DryRun         bool
+	Check          bool
+	Strict         bool
+	StrictVersions bool
+
+	// 快照、告警与通知
+	SnapshotFile        string
+	AlertsFile          string
+	AlertsWebhook       string
+	AlertsDownloadsDrop float64
+	Notify              string
+	NotifyTemplate      string
+	NotifyOn            string
+}
+
+// 运行配置（由 [CommandFlags] 校验得到）
+type RunConfig struct {
+	Client         *HTTPClient    // 共享 HTTP Client（已设置限流与缓存）
+	Endpoints      Endpoints      // 服务地址
+	GithubToken    string         // Github Token
+	Dir            string         // 相对路径的基准目录
+	PublisherList  string         // publisher 名称列表（`,`逗号分割）
+	PackageList    string         // package 名称列表（`,`逗号分割）
+	HostedPackages []PackageRef   // 自托管 pub 仓库的 package
+	Tokens         PubTokens      // dart pub token 凭据
+	Targets        []Target       // 输出目标
+	AssetsPath     string         // svg 徽章的输出目录
+	Weights        map[string]int // 健康度扣分项权重
+	Mode           UpdateMode     // 文件更新模式
+	Strict         bool           // 区块标记的警告视为错误
+	StrictVersions bool           // pub 最新版本缺少对应的 git tag 时失败
+	Fetch          FetchOptions   // 抓取选项
+	Notify         NotifyOptions  // 通知选项
+
+	ContributorsExclude []string // 排除的贡献者
+
+	SnapshotPath        string       // 运行快照文件（为空时不对比）
+	PreviousSnapshot    *Snapshot    // 上一次运行的快照（首次运行时为 nil）
+	Alerts              AlertOptions // 告警输出选项
+	AlertsDownloadsDrop float64      // 30 天下载量下降超过该百分比时告警
+}
+
 func main() {
-	var githubToken, filename, publisherList, packageList, sortField, sortMode string
-	flag.StringVar(&githubToken, "githubToken", "Github Token with repo permissions", "Github Token with repo permissions")
-	flag.StringVar(&filename, "filename", "README.md", "文件名 如: README.md")
-	flag.StringVar(&publisherList, "publisherList", "", "publisher 如: aa,bb,cc")
-	flag.StringVar(&packageList, "packageList", "", "package 如: aa,bb,cc")
-	flag.StringVar(&sortField, "sortField", "name", "name | published | pubLikes | pubDownloads | githubStars")
-	flag.StringVar(&sortMode, "sortMode", "asc", "asc | desc")
+	config, err := newRunConfig(parseCommandFlags())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(run(context.Background(), config))
+}
+
+// 解析命令行参数
+func parseCommandFlags() CommandFlags {
+	var f CommandFlags
+	flag.StringVar(&f.GithubToken, "githubToken", "Github Token with repo permissions", "Github Token with repo permissions")
+	flag.StringVar(&f.Filename, "filename", "README.md", "文件名 如: README.md")
+	flag.StringVar(&f.TargetList, "targets", "", "多个输出目标（每行一个：文件 key=value ...，未指定的选项沿用命令行参数） 如: README_CN.md sortField=pubDownloads markers=total")
+	flag.StringVar(&f.Dir, "dir", "", "相对路径（filename、targets）的基准目录，默认当前目录")
+	flag.StringVar(&f.PublisherList, "publisherList", "", "publisher 如: aa,bb,cc")
+	flag.StringVar(&f.PackageList, "packageList", "", "package 如: aa,bb,cc")
+	flag.StringVar(&f.SortField, "sortField", "name", "name | published | pubLikes | pubDownloads | githubStars | githubIssues | githubPullRequests | health")
+	flag.StringVar(&f.FilterList, "filter", "", "package 过滤条件（逗号分割，同时满足） 如: githubPullRequests>20,pubPoints>=140")
+	flag.StringVar(&f.SortMode, "sortMode", "asc", "asc | desc")
+	flag.BoolVar(&f.GroupByRepo, "groupByRepo", false, "同一 Github 仓库的 package 合并为一组展示")
+	flag.BoolVar(&f.Activity, "activity", false, "展示仓库活跃度（已归档、最近推送、默认分支 CI 状态）")
+	flag.StringVar(&f.ContributorsLayout, "contributorsLayout", contributorsStacked, "贡献者展示方式 stacked | inline | names | none")
+	flag.IntVar(&f.ContributorsCount, "contributorsCount", defaultContributorsCount, "展示的贡献者数量")
+	flag.IntVar(&f.ContributorsSize, "contributorsSize", defaultContributorsSize, "贡献者头像宽度（像素）")
+	flag.StringVar(&f.LeaderboardLayout, "leaderboardLayout", leaderboardTable, "贡献者排行展示方式 table | avatars")
+	flag.IntVar(&f.LeaderboardCount, "leaderboardCount", defaultLeaderboardCount, "贡献者排行展示数量")
+	flag.BoolVar(&f.Health, "health", false, "展示健康度列")
+	flag.StringVar(&f.HealthWeights, "healthWeights", "", "健康度扣分项权重 如: points=30,published=20,issues=10,pullRequests=10,archived=20,discontinued=30,license=10")
+	flag.IntVar(&f.AttentionCount, "attentionCount", defaultAttentionCount, "需要关注的 package 展示数量")
+	flag.IntVar(&f.AttentionThreshold, "attentionThreshold", defaultAttentionThreshold, "健康度低于该值的 package 需要关注")
+	flag.BoolVar(&f.ContributorsAnon, "contributorsAnon", false, "贡献者总数包含匿名贡献者")
+	flag.StringVar(&f.ContributorsExclude, "contributorsExclude", "", "排除的贡献者（支持通配符 *） 如: renovate-bot,*-bot")
+	flag.StringVar(&f.Locale, "locale", defaultLocale, "表格语言 en | zh-CN")
+	flag.StringVar(&f.Render, "render", renderBadge, "渲染方式 badge | native | svg")
+	flag.StringVar(&f.AssetsDir, "assetsDir", "assets/pub-dashboard", "svg 渲染时徽章的输出目录（相对于 dir）")
+	flag.StringVar(&f.IssueLabel, "issueLabel", "", "monorepo package 的 Issues / Pull_requests label 过滤 如: p: {name}")
+	flag.DurationVar(&f.RateLimitWait, "rateLimitWait", defaultRateLimitMaxWait, "命中限流后最长等待时长 如: 1m")
+	flag.StringVar(&f.HostLimits, "hostLimits", "", "按 host 限流（host=并发数:每秒请求数） 如: pub.dev=8:10,api.github.com=6:10")
+	flag.StringVar(&f.CacheDir, "cacheDir", "", "HTTP 磁盘缓存目录（为空时不缓存） 如: .cache/pub-dashboard")
+	flag.StringVar(&f.CacheTTL, "cacheTTL", "", "按接口类型的缓存新鲜期 如: package=6h,score=1h,search=1h,github=0s")
+	flag.StringVar(&f.PubURL, "pubURL", "", "pub 仓库地址（默认 PUB_HOSTED_URL 或 https://pub.dev）")
+	flag.StringVar(&f.GithubAPIURL, "githubAPIURL", "", "GitHub API 地址（默认 GITHUB_API_URL 或 https://api.github.com）")
+	flag.StringVar(&f.GithubURL, "githubURL", "", "GitHub 网页地址（默认 GITHUB_SERVER_URL 或由 githubAPIURL 推导）")
+	flag.StringVar(&f.HostedPackageList, "hostedPackageList", "", "自托管 pub 仓库的 package 如: https://pub.example.com=aa,bb;https://pub2.example.com=cc")
+	flag.StringVar(&f.PubTokens, "pubTokens", "", "dart pub token 凭据文件（默认与 dart pub token 一致）")
+	flag.BoolVar(&f.DryRun, "dry-run", false, "仅输出变化的 unified diff，不写入文件")
+	flag.BoolVar(&f.Check, "check", false, "仅检查文件是否过期（过期时退出码为 1），不写入文件")
+	flag.BoolVar(&f.Strict, "strict", false, "区块标记的警告（未知、格式错误、重复、缺失表格）视为错误")
+	flag.BoolVar(&f.StrictVersions, "strictVersions", false, "pub 最新版本缺少对应的 git tag 时失败")
+	flag.StringVar(&f.SnapshotFile, "snapshot", "", "运行快照文件（相对于 dir，与上一次运行对比生成告警） 如: .pub-dashboard/snapshot.json")
+	flag.StringVar(&f.AlertsFile, "alertsFile", "", "告警 JSON 文件（相对于 dir） 如: alerts.json")
+	flag.StringVar(&f.AlertsWebhook, "alertsWebhook", "", "告警 POST 地址")
+	flag.StringVar(&f.Notify, "notify", "", "运行结果通知（空白分割的 格式=地址，格式 slack | discord | teams | custom） 如: slack=https://hooks.slack.com/services/xxx")
+	flag.StringVar(&f.NotifyTemplate, "notifyTemplate", "", "custom 通知的 JSON 模板文件（text/template）")
+	flag.StringVar(&f.NotifyOn, "notifyOn", notifyOnChanges, "发送通知的时机 changes | always")
+	flag.Float64Var(&f.AlertsDownloadsDrop, "alertsDownloadsDrop", defaultAlertDownloadsDrop, "30 天下载量下降超过该百分比时告警（0 为不告警）")
 	flag.Parse()
+	return f
+}
+
+// 校验命令行参数并生成运行配置
+//
+// 上一次运行的快照在此读取，避免抓取完成后才发现快照无效
+//
+// 参数:
+//   - [f] 命令行参数
+//
+// 返回值:
+//   - [RunConfig] 运行配置
+//   - 错误（参数无效、文件无法读取时非 nil）
+func newRunConfig(f CommandFlags) (RunConfig, error) {
+	endpoints := newEndpoints(f.PubURL, f.GithubAPIURL, f.GithubURL)
+	if _, ok := locales[f.Locale]; !ok {
+		return RunConfig{}, fmt.Errorf("unknown locale %q", f.Locale)
+	}
+	if !slices.Contains(renderModes, f.Render) {
+		return RunConfig{}, fmt.Errorf("unknown render %q", f.Render)
+	}
+	if !slices.Contains(contributorsLayouts, f.ContributorsLayout) {
+		return RunConfig{}, fmt.Errorf("unknown contributors layout %q", f.ContributorsLayout)
+	}
+	if !slices.Contains(leaderboardLayouts, f.LeaderboardLayout) {
+		return RunConfig{}, fmt.Errorf("unknown leaderboard layout %q", f.LeaderboardLayout)
+	}
+	filter, err := parseFilter(f.FilterList)
+	if err != nil {
+		return RunConfig{}, err
+	}
+	weights, _ := parseHealthWeights(defaultHealthWeights)
+	customWeights, err := parseHealthWeights(f.HealthWeights)
+	if err != nil {
+		return RunConfig{}, err
+	}
+	maps.Copy(weights, customWeights)
+	if f.AttentionThreshold < 0 {
+		return RunConfig{}, fmt.Errorf("invalid attentionThreshold %d", f.AttentionThreshold)
+	}
+	if f.SnapshotFile == "" && (f.AlertsFile != "" || f.AlertsWebhook != "") {
+		return RunConfig{}, errors.New("alertsFile / alertsWebhook need a snapshot")
+	}
+	if !slices.Contains(notifyOnModes, f.NotifyOn) {
+		return RunConfig{}, fmt.Errorf("unknown notifyOn %q", f.NotifyOn)
+	}
+	notifySinks, err := parseNotifySinks(f.Notify)
+	if err != nil {
+		return RunConfig{}, err
+	}
+	notifyOptions := NotifyOptions{Sinks: notifySinks, On: f.NotifyOn}
+	if f.NotifyTemplate != "" {
+		notifyOptions.Template, err = parseNotifyTemplate(f.NotifyTemplate)
+		if err != nil {
+			return RunConfig{}, err
+		}
+	}
+	if slices.ContainsFunc(notifySinks, func(sink NotifySink) bool { return sink.Kind == notifyCustom }) && notifyOptions.Template == nil {
+		return RunConfig{}, errors.New("custom notify sink needs a notifyTemplate")
+	}
+	attentionThreshold := f.AttentionThreshold
+	targets, err := parseTargets(f.TargetList, Target{
+		Filename: f.Filename,
+		SortMode: f.SortMode,
+		Table: TableOptions{SortField: f.SortField, GroupByRepo: f.GroupByRepo, IssueLabel: f.IssueLabel, GithubURL: endpoints.GithubURL, Locale: f.Locale, Render: f.Render, Activity: f.Activity,
+			ContributorsLayout: f.ContributorsLayout, ContributorsCount: f.ContributorsCount, ContributorsSize: f.ContributorsSize, Health: f.Health},
+		Filter: filter,
+
+		Leaderboard: LeaderboardOptions{Layout: f.LeaderboardLayout, Count: f.LeaderboardCount},
+		Attention:   AttentionOptions{Count: f.AttentionCount, Threshold: &attentionThreshold},
+	}, f.Dir)
+	if err != nil {
+		return RunConfig{}, err
+	}
 
-	ctx := context.Background()
 	client := newHTTPClient()
+	client.RateLimitMaxWait = f.RateLimitWait
+	hostLimitList, err := parseHostLimits(f.HostLimits)
+	if err != nil {
+		return RunConfig{}, err
+	}
+	client.SetHostLimits(hostLimitList)
+	if f.CacheDir != "" {
+		ttl, _ := parseCacheTTL(defaultCacheTTL)
+		customTTL, err := parseCacheTTL(f.CacheTTL)
+		if err != nil {
+			return RunConfig{}, err
+		}
+		maps.Copy(ttl, customTTL)
+		client.Cache, err = newHTTPCache(f.CacheDir, ttl)
+		if err != nil {
+			return RunConfig{}, err
+		}
+	}
 
-	packageNames, err := mergePackageList(ctx, client, publisherList, packageList)
+	tokens, err := loadPubTokens(f.PubTokens)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return RunConfig{}, err
 	}
-	packageInfoList, err := getPackageInfo(ctx, client, githubToken, packageNames)
+	hostedPackages, err := parseHostedPackageList(f.HostedPackageList)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return RunConfig{}, err
+	}
+	mode := UpdateModeWrite
+	if f.DryRun {
+		mode = UpdateModeDryRun
+	}
+	if f.Check {
+		mode = UpdateModeCheck
+	}
+
+	config := RunConfig{
+		Client:         client,
+		Endpoints:      endpoints,
+		GithubToken:    f.GithubToken,
+		Dir:            f.Dir,
+		PublisherList:  f.PublisherList,
+		PackageList:    f.PackageList,
+		HostedPackages: hostedPackages,
+		Tokens:         tokens,
+		Targets:        targets,
+		AssetsPath:     resolvePath(f.Dir, f.AssetsDir),
+		Weights:        weights,
+		Mode:           mode,
+		Strict:         f.Strict,
+		StrictVersions: f.StrictVersions,
+		Notify:         notifyOptions,
+
+		ContributorsExclude: removeDuplicates(strings.Split(f.ContributorsExclude, ",")),
+
+		Alerts:              AlertOptions{Webhook: f.AlertsWebhook},
+		AlertsDownloadsDrop: f.AlertsDownloadsDrop,
+	}
+	config.Fetch = targetFetchOptions(targets, weights, f.StrictVersions)
+	config.Fetch.ContributorsAnon = f.ContributorsAnon
+	config.Fetch.Advisories = f.SnapshotFile != ""
+	if f.SnapshotFile != "" {
+		config.SnapshotPath = resolvePath(f.Dir, f.SnapshotFile)
+		config.PreviousSnapshot, err = loadSnapshot(config.SnapshotPath)
+		if err != nil {
+			return RunConfig{}, err
+		}
+	}
+	if f.AlertsFile != "" {
+		config.Alerts.File = resolvePath(f.Dir, f.AlertsFile)
+	}
+	return config, nil
+}
+
+// 相对路径按 [dir] 解析（绝对路径保持不变）
+func resolvePath(dir string, filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(dir, filename)
+}
+
+// 抓取 package 信息 -> 更新输出目标 -> 快照对比告警 -> 通知
+//
+// 参数:
+//   - [ctx]    上下文
+//   - [config] 运行配置
+//
+// 返回值:
+//   - 退出码（有失败，或 check 模式下文件过期时为 1）
+func run(ctx context.Context, config RunConfig) int {
+	report := RunReport{Mode: config.Mode.String(), Files: []string{}, Added: []string{}, Removed: []string{}, Failures: []string{}}
+	report.Repository, report.RunURL = githubRunInfo()
+
+	packageInfoList, err := fetchPackages(ctx, config)
+	if err != nil {
+		// 抓取失败时同样通知
+		report.fail(err)
+		report.Summary = "Failed to fetch the packages"
+		if err := sendNotifications(ctx, config.Client, report, config.Notify, config.Mode); err != nil {
+			fmt.Println(err)
+		}
+		return 1
 	}
-	sortPackageInfo(packageInfoList, sortField, sortMode)
-	markdownTable := assembleMarkdownTable(packageInfoList, sortField)
 
-	// 更新表格
-	if err := updateMarkdownTable(filename, markdownTable); err != nil {
+	updateTargets(packageInfoList, config, &report)
+	if config.SnapshotPath != "" {
+		updateSnapshot(ctx, packageInfoList, config, &report)
+	}
+	if config.StrictVersions {
+		// pub 最新版本缺少对应的 git tag 时失败（文件仍会更新）
+		for _, value := range untaggedPackages(packageInfoList) {
+			report.fail(fmt.Errorf("🏷️❌ %s v%s has no matching git tag", value.Name, value.Version))
+		}
+	}
+	failed := len(report.Failures) > 0
+	for _, value := range packageInfoList {
+		if value.Code != 1 {
+			// 不存在的 package 降级展示，不视为运行失败
+			report.Failures = append(report.Failures, "⁉️ "+value.Name+": package not found")
+		} else {
+			report.Packages++
+		}
+	}
+	report.Changed = len(report.Files) > 0
+	report.Summary = report.summary()
+	if err := sendNotifications(ctx, config.Client, report, config.Notify, config.Mode); err != nil {
+		// 通知失败不影响运行结果
 		fmt.Println(err)
-		os.Exit(1)
 	}
-	// 更新总数
-	if err := updateMarkdownPackageTotal(filename, len(packageInfoList)); err != nil {
+	if err := writeChangedOutput(report.Changed); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return 1
+	}
+	if failed || (config.Mode == UpdateModeCheck && report.Changed) {
+		return 1
+	}
+	return 0
+}
+
+// 获取全部 package 的信息（合并 publisher、自定义与自托管 package，排除贡献者并计算健康度）
+//
+// 参数:
+//   - [ctx]    上下文
+//   - [config] 运行配置
+//
+// 返回值:
+//   - [PackageInfo] 信息列表
+//   - 错误（package 列表无效或抓取失败时非 nil）
+func fetchPackages(ctx context.Context, config RunConfig) ([]PackageInfo, error) {
+	packageNames, err := mergePackageList(ctx, config.Client, config.Endpoints.PubURL, config.PublisherList, config.PackageList)
+	if err != nil {
+		return nil, err
 	}
+	packages := []PackageRef{}
+	defaultSource := PackageSource{URL: config.Endpoints.PubURL, Token: config.Tokens.tokenFor(config.Endpoints.PubURL)}
+	for _, name := range packageNames {
+		packages = append(packages, PackageRef{Name: name, Source: defaultSource})
+	}
+	for _, ref := range config.HostedPackages {
+		ref.Source.Token = config.Tokens.tokenFor(ref.Source.URL)
+		packages = append(packages, ref)
+	}
+	if err := checkDuplicatePackages(packages); err != nil {
+		return nil, err
+	}
+	packageInfoList, err := getPackageInfo(ctx, config.Client, config.Endpoints, config.GithubToken, packages, config.Fetch)
+	if err != nil {
+		return nil, err
+	}
+	excludeContributors(packageInfoList, config.ContributorsExclude)
+	now := time.Now()
+	for i := range packageInfoList {
+		packageInfoList[i].Health = computeHealth(packageInfoList[i], config.Weights, now)
+	}
+	return packageInfoList, nil
+}
+
+// 依次更新每个输出目标及 svg 徽章（某个目标失败时继续更新其余目标）
+//
+// 参数:
+//   - [packageInfoList] package 信息列表
+//   - [config]          运行配置
+//   - [report]          运行结果（记录有变化的文件与失败信息）
+func updateTargets(packageInfoList []PackageInfo, config RunConfig, report *RunReport) {
+	badges := map[string][]byte{}
+	usesSVG := false
+	for _, target := range config.Targets {
+		list := slices.Clone(filterPackageInfo(packageInfoList, target.Filter))
+		sortPackageInfo(list, target.Table.SortField, target.SortMode)
+		if target.Table.Render == renderSVG {
+			// 徽章按相对于 Markdown 文件的路径引用
+			rel, err := filepath.Rel(filepath.Dir(target.Filename), config.AssetsPath)
+			if err != nil {
+				report.fail(err)
+				continue
+			}
+			target.Table.BadgeURL = filepath.ToSlash(rel)
+			maps.Copy(badges, collectBadges(list, target.Table))
+			usesSVG = true
+		}
+		blocks := MarkdownBlocks{
+			Table:   assembleMarkdownTable(list, target.Table),
+			Total:   len(list),
+			Stats:   computePackageStats(list),
+			Markers: target.Markers,
+			Locale:  target.Table.Locale,
+
+			Leaderboard: target.Leaderboard,
+			Attention:   target.Attention,
+		}
+		targetChanged, err := updateMarkdown(target.Filename, blocks, UpdateOptions{Mode: config.Mode, Strict: config.Strict})
+		if err != nil {
+			report.fail(err)
+			continue
+		}
+		if config.Mode == UpdateModeCheck && targetChanged {
+			fmt.Printf("📄❌ %s is stale\n", target.Filename)
+		}
+		if targetChanged {
+			report.changedFile(config.Dir, target.Filename)
+		}
+	}
+	if usesSVG {
+		badgesChanged, err := updateBadges(config.AssetsPath, badges, config.Mode)
+		if err != nil {
+			report.fail(err)
+		}
+		if badgesChanged {
+			report.changedFile(config.Dir, config.AssetsPath)
+		}
+	}
+}
+
+// 与上一次运行的快照对比并输出告警（首次运行无告警），然后更新快照
+//
+// 告警输出失败不影响快照更新
+//
+// 参数:
+//   - [ctx]             上下文
+//   - [packageInfoList] package 信息列表
+//   - [config]          运行配置
+//   - [report]          运行结果（记录新增/移除的 package、有变化的文件与失败信息）
+func updateSnapshot(ctx context.Context, packageInfoList []PackageInfo, config RunConfig, report *RunReport) {
+	alerts := []Alert{}
+	currentSnapshot := newSnapshot(packageInfoList)
+	if config.PreviousSnapshot != nil {
+		alerts = compareSnapshots(*config.PreviousSnapshot, currentSnapshot, config.AlertsDownloadsDrop)
+		report.Added, report.Removed = diffSnapshotPackages(*config.PreviousSnapshot, currentSnapshot)
+	}
+	if err := reportAlerts(ctx, config.Client, alerts, config.Alerts, config.Mode); err != nil {
+		report.fail(err)
+	}
+	snapshotChanged, err := writeSnapshot(config.SnapshotPath, currentSnapshot, config.Mode)
+	if err != nil {
+		report.fail(err)
+	}
+	if snapshotChanged {
+		report.changedFile(config.Dir, config.SnapshotPath)
+	}
+}
+
+// 获取 pub 最新版本缺少对应 git tag 的 package
+//
+// 参数:
+//   - [packageInfoList] package 信息列表
+//
+// 返回值:
+//   - 缺少 git tag 的 package（未关联 GitHub 仓库的 package 不计入）
+func untaggedPackages(packageInfoList []PackageInfo) []PackageInfo {
+	list := []PackageInfo{}
+	for _, value := range packageInfoList {
+		if value.Code == 1 && value.GithubReleaseChecked && value.GithubVersionTag == "" {
+			list = append(list, value)
+		}
+	}
+	return list
 }
 
 // 合并 publisher 的 package 和自定义 package 列表，并去重（保持顺序）
@@ -179,13 +1065,14 @@ func main() {
 // 参数:
 //   - [ctx]           上下文
 //   - [client]        共享 HTTP Client
+//   - [pubURL]        pub 仓库地址
 //   - [publisherList] publisher 名称列表（逗号,分割）
 //   - [packageList]   package 名称列表（逗号,分割）
 //
 // 返回值:
 //   - 合并去重后的 package 名称列表
-func mergePackageList(ctx context.Context, client *http.Client, publisherList string, packageList string) ([]string, error) {
-	publisherPackages, err := getPublisherPackages(ctx, client, publisherList)
+func mergePackageList(ctx context.Context, client *HTTPClient, pubURL string, publisherList string, packageList string) ([]string, error) {
+	publisherPackages, err := getPublisherPackages(ctx, client, pubURL, publisherList)
 	if err != nil {
 		return nil, err
 	}
@@ -195,80 +1082,155 @@ func mergePackageList(ctx context.Context, client *http.Client, publisherList st
 
 // 通过 Publisher 获取所有 Package 名称
 //
+// 各 publisher 并发查询，实际请求速率由 HTTP 层按 host 限流。
+//
 // 参数:
 //   - [ctx]           上下文
 //   - [client]        共享 HTTP Client
+//   - [pubURL]        pub 仓库地址
 //   - [publisherName] publisher 列表（逗号,分割）
 //
 // 返回值:
 //   - package 名称列表
-func getPublisherPackages(ctx context.Context, client *http.Client, publisherName string) ([]string, error) {
-	printErrTitle := "🌏⚠️ PublisherPackages: "
+func getPublisherPackages(ctx context.Context, client *HTTPClient, pubURL string, publisherName string) ([]string, error) {
 	if strings.TrimSpace(publisherName) == "" {
 		return nil, nil
 	}
 	publisherList := removeDuplicates(strings.Split(publisherName, ","))
 	fmt.Println("🌏", publisherList)
+	publisherPackages, err := concurrentMap(ctx, publisherList, maxPackageConcurrency, func(ctx context.Context, publisher string) ([]string, error) {
+		return getPublisherAllPages(ctx, client, pubURL, publisher)
+	})
+	if err != nil {
+		return nil, err
+	}
 	packageNameList := []string{}
-	for _, publisher := range publisherList {
-		// 逐页查询，直至返回空结果
-		for pageIndex := 1; ; pageIndex++ {
-			fmt.Printf("🌏🔗 Publisher: %s, Page: %d \n", publisher, pageIndex)
-			rawURL := fmt.Sprintf("https://pub.dev/api/search?q=publisher:%s&page=%d&sort=downloads", url.QueryEscape(publisher), pageIndex)
-			body, status, err := httpGetWithRetry(ctx, client, rawURL, nil)
-			if err != nil {
-				return nil, fmt.Errorf("%s%w", printErrTitle, err)
-			}
-			// http.StatusNotFound 		不存在更多数据
-			// http.StatusBadRequest 	可能超出最多 10 页的限制
-			if status == http.StatusNotFound || status == http.StatusBadRequest {
-				break // 无更多结果
-			}
-			if status != http.StatusOK {
-				return nil, fmt.Errorf("%s%s: unexpected status %d", printErrTitle, publisher, status)
-			}
-			var data PublisherInfo
-			if err := json.Unmarshal(body, &data); err != nil {
-				return nil, fmt.Errorf("%s%w", printErrTitle, err)
-			}
-			if len(data.Packages) == 0 {
-				break
-			}
-			for _, packageName := range data.Packages {
-				if packageName.Package != "" {
-					packageNameList = append(packageNameList, packageName.Package)
-				}
-			}
-		}
+	for _, packageNames := range publisherPackages {
+		packageNameList = append(packageNameList, packageNames...)
 	}
 	return removeDuplicates(packageNameList), nil
 }
 
-// 获取所有 Package 信息（并发抓取）
+// 获取单个 Publisher 所有分页的 Package 名称
 //
-// 以 [maxConcurrency] 为上限并发处理每个 package，结果按输入顺序返回，保证排序前顺序确定。
-// 任一 package 抓取失败将取消其余请求并整体返回错误。
+// 先查询第 1 页，存在下一页时按批并发查询后续页（每批 [publisherPageConcurrency] 页，最多 [maxPublisherPages] 页），
+// 某页无 next 后不再查询下一批，按页序合并至该页为止。
+//
+// 参数:
+//   - [ctx]       上下文
+//   - [client]    共享 HTTP Client
+//   - [pubURL]    pub 仓库地址
+//   - [publisher] publisher 名称
+//
+// 返回值:
+//   - package 名称列表（按页序）
+func getPublisherAllPages(ctx context.Context, client *HTTPClient, pubURL string, publisher string) ([]string, error) {
+	packageNameList, hasNext, err := getPublisherPage(ctx, client, pubURL, publisher, 1)
+	if err != nil || !hasNext {
+		return packageNameList, err
+	}
+	type page struct {
+		packageNames []string
+		hasNext      bool
+	}
+	for first := 2; first <= maxPublisherPages; first += publisherPageConcurrency {
+		pageIndexList := []int{}
+		for pageIndex := first; pageIndex < first+publisherPageConcurrency && pageIndex <= maxPublisherPages; pageIndex++ {
+			pageIndexList = append(pageIndexList, pageIndex)
+		}
+		pages, err := concurrentMap(ctx, pageIndexList, publisherPageConcurrency, func(ctx context.Context, pageIndex int) (page, error) {
+			packageNames, hasNext, err := getPublisherPage(ctx, client, pubURL, publisher, pageIndex)
+			return page{packageNames, hasNext}, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pages {
+			packageNameList = append(packageNameList, p.packageNames...)
+			if !p.hasNext {
+				return packageNameList, nil // 无更多结果
+			}
+		}
+	}
+	fmt.Printf("🌏⚠️ Publisher: %s, stopped at page %d\n", publisher, maxPublisherPages)
+	return packageNameList, nil
+}
+
+// 获取 Publisher 单页的 Package 名称
+//
+// 参数:
+//   - [ctx]       上下文
+//   - [client]    共享 HTTP Client
+//   - [pubURL]    pub 仓库地址
+//   - [publisher] publisher 名称
+//   - [pageIndex] 页码（从 1 开始）
+//
+// 返回值:
+//   - package 名称列表（无更多结果时为空）
+//   - 是否存在下一页
+func getPublisherPage(ctx context.Context, client *HTTPClient, pubURL string, publisher string, pageIndex int) ([]string, bool, error) {
+	printErrTitle := "🌏⚠️ PublisherPackages: "
+	fmt.Printf("🌏🔗 Publisher: %s, Page: %d \n", publisher, pageIndex)
+	rawURL := fmt.Sprintf("%s/api/search?q=publisher:%s&page=%d&sort=downloads", pubURL, url.QueryEscape(publisher), pageIndex)
+	body, status, err := httpGetWithRetry(ctx, client, rawURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	// http.StatusNotFound 		不存在更多数据
+	// http.StatusBadRequest 	可能超出最多 10 页的限制
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		return nil, false, nil // 无更多结果
+	}
+	if status != http.StatusOK {
+		return nil, false, fmt.Errorf("%s%s: unexpected status %d", printErrTitle, publisher, status)
+	}
+	var data PublisherInfo
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	packageNameList := []string{}
+	for _, packageName := range data.Packages {
+		if packageName.Package != "" {
+			packageNameList = append(packageNameList, packageName.Package)
+		}
+	}
+	return packageNameList, len(packageNameList) > 0 && data.Next != "", nil
+}
+
+// 获取所有 Package 信息（并发抓取）
+//
+// 以 [maxPackageConcurrency] 为上限并发处理每个 package，结果按输入顺序返回，保证排序前顺序确定。
+// 任一 package 抓取失败将取消其余请求并整体返回错误。
 //
 // 参数:
 //   - [ctx]          上下文
 //   - [client]       共享 HTTP Client
+//   - [endpoints]    服务地址
 //   - [githubToken]  Github Token
-//   - [packageNames] package 名称列表（已去重清洗）
+//   - [packages]     package 列表（已去重清洗）
+//   - [options]      抓取选项
 //
 // 返回值:
-//   - [PackageInfo] 列表（与 packageNames 顺序一致）
-func getPackageInfo(ctx context.Context, client *http.Client, githubToken string, packageNames []string) ([]PackageInfo, error) {
+//   - [PackageInfo] 列表（与 packages 顺序一致）
+func getPackageInfo(ctx context.Context, client *HTTPClient, endpoints Endpoints, githubToken string, packages []PackageRef, options FetchOptions) ([]PackageInfo, error) {
+	if options.repos == nil {
+		options.repos = &githubRepoCache{repos: map[string]*githubRepoEntry{}}
+	}
+	packageNames := make([]string, len(packages))
+	for i, ref := range packages {
+		packageNames[i] = ref.Name
+	}
 	fmt.Println("📦", packageNames)
-	return concurrentMap(ctx, packageNames, maxConcurrency, func(ctx context.Context, name string) (PackageInfo, error) {
-		fmt.Println("📦🔥 " + name)
-		info, err := fetchPackage(ctx, client, githubToken, name)
+	return concurrentMap(ctx, packages, maxPackageConcurrency, func(ctx context.Context, ref PackageRef) (PackageInfo, error) {
+		fmt.Println("📦🔥 " + ref.Name)
+		info, err := fetchPackage(ctx, client, endpoints, githubToken, ref, options)
 		if err != nil {
 			return PackageInfo{}, err
 		}
 		if info.Code == 1 {
-			fmt.Printf("📦✅ %s, Code: 1\n", name)
+			fmt.Printf("📦✅ %s, Code: 1\n", ref.Name)
 		} else {
-			fmt.Printf("📦❌ %s, Code: 0\n", name)
+			fmt.Printf("📦❌ %s, Code: 0\n", ref.Name)
 		}
 		return info, nil
 	})
@@ -279,20 +1241,23 @@ func getPackageInfo(ctx context.Context, client *http.Client, githubToken string
 // 参数:
 //   - [ctx]         上下文
 //   - [client]      共享 HTTP Client
+//   - [endpoints]   服务地址
 //   - [githubToken] Github Token
-//   - [name]        package 名称
+//   - [ref]         package 名称及来源
+//   - [options]     抓取选项
 //
 // 返回值:
 //   - [PackageInfo]，包不存在时 Code=0（降级展示为 ⁉️，非错误）
-func fetchPackage(ctx context.Context, client *http.Client, githubToken string, name string) (PackageInfo, error) {
+func fetchPackage(ctx context.Context, client *HTTPClient, endpoints Endpoints, githubToken string, ref PackageRef, options FetchOptions) (PackageInfo, error) {
 	printErrTitle := "📦⚠️ PackageInfo: "
-	body, status, err := httpGetWithRetry(ctx, client, fmt.Sprintf("https://pub.dev/api/packages/%s", name), nil)
+	name := ref.Name
+	body, status, err := httpGetWithRetry(ctx, client, fmt.Sprintf("%s/api/packages/%s", ref.Source.URL, name), pubHeaders(ref.Source))
 	if err != nil {
 		return PackageInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
 	}
 	// 404：包不存在 -> 降级
 	if status == http.StatusNotFound {
-		return PackageInfo{Code: 0, Name: name}, nil
+		return PackageInfo{Code: 0, Name: name, PubURL: ref.Source.URL, Hosted: ref.Source.Hosted}, nil
 	}
 	if status != http.StatusOK {
 		return PackageInfo{}, fmt.Errorf("%s%s: unexpected status %d", printErrTitle, name, status)
@@ -302,50 +1267,101 @@ func fetchPackage(ctx context.Context, client *http.Client, githubToken string,
 		return PackageInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
 	}
 	if data.Name == "" {
-		return PackageInfo{Code: 0, Name: name}, nil
+		return PackageInfo{Code: 0, Name: name, PubURL: ref.Source.URL, Hosted: ref.Source.Hosted}, nil
 	}
 
 	packageInfo := PackageInfo{
 		Code:         1,
 		Name:         data.Name,
+		PubURL:       ref.Source.URL,
+		Hosted:       ref.Source.Hosted,
 		Version:      data.Latest.Pubspec.Version,
 		Description:  data.Latest.Pubspec.Description,
 		Homepage:     data.Latest.Pubspec.Homepage,
 		Repository:   data.Latest.Pubspec.Repository,
 		IssueTracker: data.Latest.Pubspec.IssueTracker,
 		Published:    data.Latest.Published,
+		Discontinued: data.IsDiscontinued,
 	}
 
-	scoreInfo, err := getPackageScoreInfo(ctx, client, data.Name)
+	scoreInfo, err := getPackageScoreInfo(ctx, client, ref.Source, data.Name)
 	if err != nil {
 		return PackageInfo{}, err
 	}
 	packageInfo.ScoreInfo = scoreInfo
 
-	if err := getGithubInfo(ctx, client, githubToken, &packageInfo); err != nil {
+	if options.Advisories {
+		advisories, err := getPackageAdvisories(ctx, client, ref.Source, data.Name)
+		if err != nil {
+			return PackageInfo{}, err
+		}
+		packageInfo.Advisories = advisories
+	}
+
+	if err := getGithubInfo(ctx, client, endpoints, githubToken, &packageInfo, options); err != nil {
 		return PackageInfo{}, err
 	}
 	return packageInfo, nil
 }
 
+// 获取 Package 安全公告
+//
+// 参数:
+//   - [ctx]         上下文
+//   - [client]      共享 HTTP Client
+//   - [source]      package 来源
+//   - [packageName] 单个 package 名称
+//
+// 返回值:
+//   - 安全公告 ID 列表（404 时为空；自托管仓库不支持该接口时同样为空）
+func getPackageAdvisories(ctx context.Context, client *HTTPClient, source PackageSource, packageName string) ([]string, error) {
+	printErrTitle := "📦⚠️ PackageAdvisories: "
+	body, status, err := httpGetWithRetry(ctx, client, fmt.Sprintf("%s/api/packages/%s/advisories", source.URL, packageName), pubHeaders(source))
+	if err != nil {
+		return nil, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	// 安全公告接口不在 Hosted Pub Repository 规范内
+	if status == http.StatusNotFound || (source.Hosted && status != http.StatusOK) {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s%s: unexpected status %d", printErrTitle, packageName, status)
+	}
+	var data PackageAdvisoriesInfo
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	advisories := []string{}
+	for _, advisory := range data.Advisories {
+		advisories = append(advisories, advisory.Id)
+	}
+	return advisories, nil
+}
+
 // 获取 Package score 信息
 //
 // 参数:
 //   - [ctx]         上下文
 //   - [client]      共享 HTTP Client
+//   - [source]      package 来源
 //   - [packageName] 单个 package 名称
 //
 // 返回值:
-//   - [PackageScoreInfo] 信息（404 时降级为空）
-func getPackageScoreInfo(ctx context.Context, client *http.Client, packageName string) (PackageScoreInfo, error) {
+//   - [PackageScoreInfo] 信息（404 时降级为空；自托管仓库不支持评分接口时同样降级为空）
+func getPackageScoreInfo(ctx context.Context, client *HTTPClient, source PackageSource, packageName string) (PackageScoreInfo, error) {
 	printErrTitle := "📦⚠️ PackageScoreInfo: "
-	body, status, err := httpGetWithRetry(ctx, client, fmt.Sprintf("https://pub.dev/api/packages/%s/score", packageName), nil)
+	body, status, err := httpGetWithRetry(ctx, client, fmt.Sprintf("%s/api/packages/%s/score", source.URL, packageName), pubHeaders(source))
 	if err != nil {
 		return PackageScoreInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
 	}
 	if status == http.StatusNotFound {
 		return PackageScoreInfo{}, nil // 无评分数据 -> 降级
 	}
+	// 评分接口不在 Hosted Pub Repository 规范内，自托管仓库可能不支持
+	if source.Hosted && status != http.StatusOK {
+		fmt.Printf("%s%s: score unavailable (status %d)\n", printErrTitle, packageName, status)
+		return PackageScoreInfo{}, nil
+	}
 	if status != http.StatusOK {
 		return PackageScoreInfo{}, fmt.Errorf("%s%s: unexpected status %d", printErrTitle, packageName, status)
 	}
@@ -371,407 +1387,3815 @@ func getPackageScoreInfo(ctx context.Context, client *http.Client, packageName s
 // 参数:
 //   - [ctx]         上下文
 //   - [client]      共享 HTTP Client
+//   - [endpoints]   服务地址
 //   - [githubToken] Github Token
 //   - [packageInfo] 当前 package 信息
-func getGithubInfo(ctx context.Context, client *http.Client, githubToken string, packageInfo *PackageInfo) error {
+//   - [options]     抓取选项
+func getGithubInfo(ctx context.Context, client *HTTPClient, endpoints Endpoints, githubToken string, packageInfo *PackageInfo, options FetchOptions) error {
 	if packageInfo.Code == 0 {
 		return nil
 	}
 	// 依次尝试 Repository、IssueTracker、Homepage 解析 Github 地址，取首个命中
 	for _, link := range []string{packageInfo.Repository, packageInfo.IssueTracker, packageInfo.Homepage} {
-		if user, repo := formatGithubInfo(link); repo != "" {
+		if user, repo, ref, path := formatGithubInfo(link, endpoints.GithubURL); repo != "" {
 			packageInfo.GithubUser = user
 			packageInfo.GithubRepo = repo
+			packageInfo.GithubRef = ref
+			packageInfo.GithubPath = path
+			break
+		}
+	}
+	if packageInfo.GithubUser == "" || packageInfo.GithubRepo == "" {
+		return nil
+	}
+
+	info, err := options.repos.get(packageInfo.GithubUser+"/"+packageInfo.GithubRepo, func() (githubRepoInfo, error) {
+		return getGithubRepoInfo(ctx, client, endpoints.GithubAPIURL, githubToken, packageInfo.GithubUser, packageInfo.GithubRepo, options)
+	})
+	if err != nil {
+		return err
+	}
+	packageInfo.GithubBaseInfo = info.BaseInfo
+	packageInfo.GithubBaseInfo.ContributorsTotal = info.ContributorsTotal
+	// 复制一份，排除贡献者时原地修改不影响同仓库的其他 package
+	packageInfo.GithubContributorsInfo = slices.Clone(info.Contributors)
+	packageInfo.GithubOpenIssues = info.OpenCounts.Issues
+	packageInfo.GithubOpenPullRequests = info.OpenCounts.PullRequests
+	packageInfo.GithubCIStatus = info.CIStatus
+	if packageInfo.GithubPath != "" && options.OpenCounts {
+		// monorepo 子目录中的 package，按 label 获取数量（同一 label 只请求一次）
+		for _, issueLabel := range options.IssueLabels {
+			label := strings.ReplaceAll(issueLabel, "{name}", packageInfo.Name)
+			if _, ok := packageInfo.GithubLabelCounts[label]; ok {
+				continue
+			}
+			counts, err := getGithubOpenCounts(ctx, client, endpoints.GithubAPIURL, githubToken, packageInfo.GithubUser, packageInfo.GithubRepo, label)
+			if err != nil {
+				return err
+			}
+			if packageInfo.GithubLabelCounts == nil {
+				packageInfo.GithubLabelCounts = map[string]GithubOpenCounts{}
+			}
+			packageInfo.GithubLabelCounts[label] = counts
+		}
+	}
+	if info.Tags == nil {
+		return nil // 仓库不存在或未获取
+	}
+	packageInfo.GithubReleaseChecked = true
+	packageInfo.GithubReleaseTag = info.ReleaseTag
+	for _, tag := range info.Tags {
+		if matchVersionTag(tag, packageInfo.Name, packageInfo.Version) {
+			packageInfo.GithubVersionTag = tag
+			break
+		}
+	}
+	if packageInfo.GithubVersionTag == "" && info.TagsTruncated {
+		// 首页未找到，按候选名称逐个查询
+		tag, err := findGithubVersionTag(ctx, client, endpoints.GithubAPIURL, githubToken, packageInfo.GithubUser, packageInfo.GithubRepo, packageInfo.Name, packageInfo.Version)
+		if err != nil {
+			return err
+		}
+		packageInfo.GithubVersionTag = tag
+	}
+	return nil
+}
+
+// 获取仓库的 Github 信息，同一仓库只调用一次 [fetch]（并发调用时等待首次结果）
+//
+// 参数:
+//   - [key]   仓库 user/repo（不区分大小写）
+//   - [fetch] 获取仓库信息
+//
+// 返回值:
+//   - 仓库信息
+func (c *githubRepoCache) get(key string, fetch func() (githubRepoInfo, error)) (githubRepoInfo, error) {
+	if c == nil {
+		return fetch()
+	}
+	key = strings.ToLower(key)
+	c.mu.Lock()
+	entry, ok := c.repos[key]
+	if !ok {
+		entry = &githubRepoEntry{}
+		c.repos[key] = entry
+	}
+	c.mu.Unlock()
+	entry.once.Do(func() {
+		entry.info, entry.err = fetch()
+	})
+	return entry.info, entry.err
+}
+
+// 获取单个 Github 仓库的信息（基础信息 -> 贡献者 -> Issues / Pull requests 数量 -> CI 状态 -> tags / release）
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [options]      抓取选项
+//
+// 返回值:
+//   - 仓库信息（仓库不存在或未获取 tags 时 Tags 为 nil）
+func getGithubRepoInfo(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, options FetchOptions) (githubRepoInfo, error) {
+	var info githubRepoInfo
+	var err error
+	info.BaseInfo, err = getGithubBaseInfo(ctx, client, githubAPIURL, githubToken, user, repo)
+	if err != nil {
+		return githubRepoInfo{}, err
+	}
+
+	info.Contributors, info.ContributorsTotal, err = getGithubContributorsInfo(ctx, client, githubAPIURL, githubToken, user, repo, options.ContributorsAnon)
+	if err != nil {
+		return githubRepoInfo{}, err
+	}
+
+	// open_issues_count 包含 Pull requests，需单独获取 Issues / Pull requests 数量
+	if options.OpenCounts {
+		info.OpenCounts, err = getGithubOpenCounts(ctx, client, githubAPIURL, githubToken, user, repo, "")
+		if err != nil {
+			return githubRepoInfo{}, err
+		}
+	}
+
+	if branch := info.BaseInfo.DefaultBranch; options.Activity && branch != "" {
+		info.CIStatus, err = getGithubCIStatus(ctx, client, githubAPIURL, githubToken, user, repo, branch)
+		if err != nil {
+			return githubRepoInfo{}, err
+		}
+	}
+
+	// GitHub release 与 tags，用于检查 pub 版本是否已打 tag / 发布 release
+	if !options.Releases {
+		return info, nil
+	}
+	info.Tags, info.TagsTruncated, err = getGithubTags(ctx, client, githubAPIURL, githubToken, user, repo)
+	if err != nil {
+		return githubRepoInfo{}, err
+	}
+	if info.Tags == nil {
+		return info, nil // 仓库不存在
+	}
+	info.ReleaseTag, err = getGithubLatestRelease(ctx, client, githubAPIURL, githubToken, user, repo)
+	if err != nil {
+		return githubRepoInfo{}, err
+	}
+	return info, nil
+}
+
+// 获取 Github 仓库 tags 的首页（最多 100 个，按名称排序）
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//
+// 返回值:
+//   - tag 名称列表（404 时为 nil，无 tag 时为空列表）
+//   - 是否还有下一页
+func getGithubTags(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string) ([]string, bool, error) {
+	printErrTitle := "📦⚠️ GithubTags: "
+	rawURL := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=100", githubAPIURL, user, repo)
+	res, err := httpGetResponse(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return nil, false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if res.Status == http.StatusNotFound {
+		return nil, false, nil // 仓库不存在 -> 降级
+	}
+	if res.Status != http.StatusOK {
+		return nil, false, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, res.Status)
+	}
+	var data []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(res.Body, &data); err != nil {
+		return nil, false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	tags := []string{}
+	for _, tag := range data {
+		tags = append(tags, tag.Name)
+	}
+	return tags, linkLastPage(res.Header.Get("Link")) > 1, nil
+}
+
+// 按候选名称（见 [versionTagCandidates]）逐个查询 pub 版本对应的 git tag
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [name]         package 名称
+//   - [version]      package 版本
+//
+// 返回值:
+//   - 首个存在的 tag（均不存在时为空）
+func findGithubVersionTag(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, name string, version string) (string, error) {
+	printErrTitle := "📦⚠️ GithubTagRef: "
+	for _, tag := range versionTagCandidates(name, version) {
+		rawURL := fmt.Sprintf("%s/repos/%s/%s/git/ref/tags/%s", githubAPIURL, user, repo, (&url.URL{Path: tag}).EscapedPath())
+		_, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
+		if err != nil {
+			return "", fmt.Errorf("%s%w", printErrTitle, err)
+		}
+		switch status {
+		case http.StatusOK:
+			return tag, nil
+		case http.StatusNotFound:
+			continue
+		}
+		return "", fmt.Errorf("%s%s/%s: %s: unexpected status %d", printErrTitle, user, repo, tag, status)
+	}
+	return "", nil
+}
+
+// 获取 Github 仓库最新 release 的 tag
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//
+// 返回值:
+//   - 最新 release 的 tag（无 release 时为空）
+func getGithubLatestRelease(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string) (string, error) {
+	printErrTitle := "📦⚠️ GithubLatestRelease: "
+	rawURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", githubAPIURL, user, repo)
+	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return "", fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if status == http.StatusNotFound {
+		return "", nil // 无 release
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "", fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	return data.TagName, nil
+}
+
+// package 名称前缀与版本之间的分隔符（见 [matchVersionTag]）
+var versionTagSeparators = []string{"-", "_", "@", "/"}
+
+// pub 版本可能对应的 git tag（[matchVersionTag] 支持的全部格式）
+//
+// 参数:
+//   - [name]    package 名称
+//   - [version] package 版本
+//
+// 返回值:
+//   - 候选 tag，例如：["1.2.3", "v1.2.3", "foo-1.2.3", "foo-v1.2.3", ...]
+func versionTagCandidates(name string, version string) []string {
+	if version == "" {
+		return nil
+	}
+	candidates := []string{version, "v" + version}
+	for _, sep := range versionTagSeparators {
+		candidates = append(candidates, name+sep+version, name+sep+"v"+version)
+	}
+	return candidates
+}
+
+// git tag 是否对应 package 版本
+//
+// 支持的 tag 格式（以 foo 1.2.3 为例）：1.2.3、v1.2.3，
+// 以及 monorepo 常用的 foo-v1.2.3、foo_v1.2.3、foo@1.2.3、foo/v1.2.3 等
+//
+// 参数:
+//   - [tag]     git tag
+//   - [name]    package 名称
+//   - [version] package 版本
+func matchVersionTag(tag string, name string, version string) bool {
+	if version == "" {
+		return false
+	}
+	for _, sep := range versionTagSeparators {
+		if rest, ok := strings.CutPrefix(tag, name+sep); ok {
+			tag = rest
 			break
 		}
 	}
-	if packageInfo.GithubUser == "" || packageInfo.GithubRepo == "" {
-		return nil
-	}
+	return strings.TrimPrefix(tag, "v") == version
+}
+
+// 获取 pub 版本与 GitHub 不一致的原因
+//
+// 参数:
+//   - [value]  package 信息
+//   - [locale] 语言包
+//
+// 返回值:
+//   - 原因列表（一致或未获取时为空），例如：["no git tag", "latest release: v1.2.2"]
+func releaseMismatches(value PackageInfo, locale Locale) []string {
+	if value.Code != 1 || !value.GithubReleaseChecked {
+		return nil
+	}
+	reasons := []string{}
+	if value.GithubVersionTag == "" {
+		reasons = append(reasons, locale.NoTag)
+	}
+	if value.GithubReleaseTag != "" && !matchVersionTag(value.GithubReleaseTag, value.Name, value.Version) {
+		reasons = append(reasons, locale.LatestRelease+": "+value.GithubReleaseTag)
+	}
+	return reasons
+}
+
+// 获取 Github 分支最新提交的 CI 状态（汇总所有 check runs）
+//
+// 任一失败（failure、timed_out、cancelled、action_required）为 failure，
+// 否则任一未完成为 pending，全部完成为 success
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [branch]       分支
+//
+// 返回值:
+//   - CI 状态 [ciSuccess] | [ciFailure] | [ciPending]（无 check runs、无权限或 404 时为空）
+func getGithubCIStatus(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, branch string) (string, error) {
+	printErrTitle := "📦⚠️ GithubCIStatus: "
+	rawURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s/check-runs?per_page=100", githubAPIURL, user, repo, url.PathEscape(branch))
+	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return "", fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	// 404（仓库不存在）/ 403（Token 无 checks 权限）/ 422（空仓库）-> 降级
+	if status == http.StatusNotFound || status == http.StatusForbidden || status == http.StatusUnprocessableEntity {
+		return "", nil
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data GithubCheckRuns
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "", fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if len(data.CheckRuns) == 0 {
+		return "", nil
+	}
+	result := ciSuccess
+	for _, run := range data.CheckRuns {
+		switch {
+		case slices.Contains([]string{"failure", "timed_out", "cancelled", "action_required", "startup_failure"}, run.Conclusion):
+			return ciFailure, nil
+		case run.Status != "completed":
+			result = ciPending
+		}
+	}
+	return result, nil
+}
+
+// 获取 Github open Issues / Pull requests 数量
+//
+// 有 Token 时通过一次 GraphQL 查询获取两者，否则通过 search 接口的 total_count 获取
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [label]        label 过滤（为空时不过滤）
+//
+// 返回值:
+//   - [GithubOpenCounts] 数量（仓库不存在时为 0）
+func getGithubOpenCounts(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, label string) (GithubOpenCounts, error) {
+	if githubToken != "" {
+		return getGithubOpenCountsGraphQL(ctx, client, githubAPIURL, githubToken, user, repo, label)
+	}
+	var counts GithubOpenCounts
+	var err error
+	counts.Issues, err = searchGithubIssuesCount(ctx, client, githubAPIURL, githubToken, user, repo, "issue", label)
+	if err != nil {
+		return GithubOpenCounts{}, err
+	}
+	counts.PullRequests, err = searchGithubIssuesCount(ctx, client, githubAPIURL, githubToken, user, repo, "pr", label)
+	if err != nil {
+		return GithubOpenCounts{}, err
+	}
+	return counts, nil
+}
+
+// 通过 GraphQL 获取 Github open Issues / Pull requests 数量（参数见 [getGithubOpenCounts]）
+func getGithubOpenCountsGraphQL(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, label string) (GithubOpenCounts, error) {
+	printErrTitle := "📦⚠️ GithubOpenCounts: "
+	filter, params := "states: OPEN", ""
+	variables := map[string]any{"owner": user, "name": repo}
+	if label != "" {
+		filter, params = "states: OPEN, labels: $labels", ", $labels: [String!]"
+		variables["labels"] = []string{label}
+	}
+	query := "query($owner: String!, $name: String!" + params + ") { repository(owner: $owner, name: $name) { " +
+		"issues(" + filter + ") { totalCount } pullRequests(" + filter + ") { totalCount } } }"
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return GithubOpenCounts{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	body, status, err := httpPostWithRetry(ctx, client, githubGraphQLURL(githubAPIURL), githubHeaders(githubToken), payload)
+	if err != nil {
+		return GithubOpenCounts{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if status != http.StatusOK {
+		return GithubOpenCounts{}, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data struct {
+		Data struct {
+			Repository *struct {
+				Issues       struct{ TotalCount int } `json:"issues"`
+				PullRequests struct{ TotalCount int } `json:"pullRequests"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return GithubOpenCounts{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if repository := data.Data.Repository; repository != nil {
+		return GithubOpenCounts{Issues: repository.Issues.TotalCount, PullRequests: repository.PullRequests.TotalCount}, nil
+	}
+	if len(data.Errors) > 0 && data.Errors[0].Type != "NOT_FOUND" {
+		return GithubOpenCounts{}, fmt.Errorf("%s%s/%s: %s", printErrTitle, user, repo, data.Errors[0].Message)
+	}
+	return GithubOpenCounts{}, nil // 仓库不存在 -> 降级
+}
+
+// 通过 search 接口统计 Github open Issues 或 Pull requests 数量
+//
+// 参数:
+//   - [ctx]          上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [kind]         issue | pr
+//   - [label]        label 过滤（为空时不过滤）
+//
+// 返回值:
+//   - 数量（仓库不存在时为 0）
+func searchGithubIssuesCount(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, kind string, label string) (int, error) {
+	printErrTitle := "📦⚠️ GithubSearchIssues: "
+	query := "repo:" + user + "/" + repo + " is:" + kind + " is:open"
+	if label != "" {
+		query += ` label:"` + label + `"`
+	}
+	rawURL := fmt.Sprintf("%s/search/issues?q=%s&per_page=1", githubAPIURL, url.QueryEscape(query))
+	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return 0, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if status == http.StatusNotFound || status == http.StatusUnprocessableEntity {
+		return 0, nil // 仓库不存在（search 接口返回 422） -> 降级
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data struct {
+		TotalCount int `json:"total_count"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	return data.TotalCount, nil
+}
+
+// 获取 GitHub GraphQL 地址，例如：https://api.github.com/graphql、https://ghe.example.com/api/graphql
+func githubGraphQLURL(githubAPIURL string) string {
+	if base, ok := strings.CutSuffix(githubAPIURL, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return githubAPIURL + "/graphql"
+}
+
+// 统计 Github 列表接口的总数
+//
+// 请求地址需携带 per_page=1，总数即 Link 响应头中最后一页的页码（仅一页时为返回的数量）
+//
+// 参数:
+//   - [ctx]         上下文
+//   - [client]      共享 HTTP Client
+//   - [rawURL]      请求地址，例如："https://api.github.com/repos/org/repo/contributors?per_page=1"
+//   - [githubToken] Github Token
+//
+// 返回值:
+//   - 总数（非 200 时为 0）
+//   - HTTP 状态码
+//   - 错误
+func countGithubList(ctx context.Context, client *HTTPClient, rawURL string, githubToken string) (int, int, error) {
+	res, err := httpGetResponse(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil || res.Status != http.StatusOK {
+		return 0, res.Status, err
+	}
+	if last := linkLastPage(res.Header.Get("Link")); last > 0 {
+		return last, res.Status, nil
+	}
+	var data []json.RawMessage
+	if err := json.Unmarshal(res.Body, &data); err != nil {
+		return 0, res.Status, err
+	}
+	return len(data), res.Status, nil
+}
+
+// 匹配 Link 响应头中的最后一页
+var linkLastRegexp = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="last"`)
+
+// 解析 Link 响应头中最后一页的页码
+//
+// 参数:
+//   - [link] Link 响应头，例如：`<https://api.github.com/...&page=2>; rel="next", <https://api.github.com/...&page=34>; rel="last"`
+//
+// 返回值:
+//   - 最后一页的页码（无最后一页时为 0）
+func linkLastPage(link string) int {
+	match := linkLastRegexp.FindStringSubmatch(link)
+	if match == nil {
+		return 0
+	}
+	u, err := url.Parse(match[1])
+	if err != nil {
+		return 0
+	}
+	page, err := strconv.Atoi(u.Query().Get("page"))
+	if err != nil {
+		return 0
+	}
+	return page
+}
+
+// 构造 pub 仓库 API 通用请求头
+func pubHeaders(source PackageSource) map[string]string {
+	headers := map[string]string{
+		"Accept": "application/vnd.pub.v2+json",
+	}
+	if source.Token != "" {
+		headers["Authorization"] = "Bearer " + source.Token
+	}
+	return headers
+}
+
+// 解析自托管 pub 仓库的 package 列表
+//
+// 参数:
+//   - [value] 自托管 package 列表（仓库之间 `;` 分号或换行分割，package 之间 `,` 逗号分割），
+//     例如："https://pub.example.com=aa,bb;https://pub2.example.com=cc"
+//
+// 返回值:
+//   - package 列表（Source 仅包含 URL 与 Hosted）
+func parseHostedPackageList(value string) ([]PackageRef, error) {
+	packages := []PackageRef{}
+	for _, item := range removeDuplicates(strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '\n' })) {
+		// 按最后一个 `=` 分割，URL 中可能包含 `=`（query）
+		i := strings.LastIndex(item, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid hosted package list %q, want url=aa,bb", item)
+		}
+		sourceURL := strings.TrimRight(strings.TrimSpace(item[:i]), "/")
+		if u, err := url.Parse(sourceURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid hosted package list %q: invalid url %q", item, sourceURL)
+		}
+		for _, name := range removeDuplicates(strings.Split(item[i+1:], ",")) {
+			packages = append(packages, PackageRef{Name: name, Source: PackageSource{URL: sourceURL, Hosted: true}})
+		}
+	}
+	if err := checkDuplicatePackages(packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// 检查不同来源的同名 package
+//
+// 快照、SVG 徽章文件名等均以 package 名称为键，同名 package 会互相覆盖，因此不允许重名。
+//
+// 参数:
+//   - [packages] package 列表
+//
+// 返回值:
+//   - 存在同名 package 时返回错误
+func checkDuplicatePackages(packages []PackageRef) error {
+	sources := map[string]string{}
+	for _, ref := range packages {
+		if sourceURL, ok := sources[ref.Name]; ok {
+			return fmt.Errorf("duplicate package %q in %s and %s", ref.Name, sourceURL, ref.Source.URL)
+		}
+		sources[ref.Name] = ref.Source.URL
+	}
+	return nil
+}
+
+// 获取 dart pub token 凭据文件的默认路径（与 `dart pub token` 一致）
+func defaultPubTokensFile() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "dart", "pub-tokens.json")
+}
+
+// 读取 dart pub token 凭据文件
+//
+// 参数:
+//   - [filename] 凭据文件，为空时读取默认路径（默认路径不存在时不视为错误）
+//
+// 返回值:
+//   - [PubTokens] 凭据
+func loadPubTokens(filename string) (PubTokens, error) {
+	printErrTitle := "🔑❌ PubTokens: "
+	isDefault := filename == ""
+	if isDefault {
+		filename = defaultPubTokensFile()
+	}
+	if filename == "" {
+		return PubTokens{}, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if isDefault && errors.Is(err, os.ErrNotExist) {
+			return PubTokens{}, nil
+		}
+		return PubTokens{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	var tokens PubTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return PubTokens{}, fmt.Errorf("%s%s: %w", printErrTitle, filename, err)
+	}
+	return tokens, nil
+}
+
+// 获取 pub 仓库对应的 Token（按 URL 前缀匹配，取最长匹配）
+//
+// 参数:
+//   - [pubURL] pub 仓库地址
+//
+// 返回值:
+//   - Token（未配置时为空）
+func (t PubTokens) tokenFor(pubURL string) string {
+	pubURL = strings.TrimRight(pubURL, "/")
+	token, matched := "", ""
+	for _, repository := range t.HostedPubRepositories {
+		repositoryURL := strings.TrimRight(repository.URL, "/")
+		if repositoryURL == "" || len(repositoryURL) <= len(matched) {
+			continue
+		}
+		if pubURL != repositoryURL && !strings.HasPrefix(pubURL, repositoryURL+"/") {
+			continue
+		}
+		value := repository.Token
+		if repository.Env != "" {
+			value = os.Getenv(repository.Env)
+		}
+		token, matched = value, repositoryURL
+	}
+	return token
+}
+
+// 构造 GitHub API 通用请求头
+func githubHeaders(githubToken string) map[string]string {
+	return map[string]string{
+		"Authorization":        "bearer " + githubToken,
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2026-03-10",
+	}
+}
+
+// 获取 Github 基础信息
+//
+// 参数:
+//   - [ctx]         上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//
+// 返回值:
+//   - [GithubBaseInfo] 信息（404 时降级为空）
+func getGithubBaseInfo(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string) (GithubBaseInfo, error) {
+	printErrTitle := "📦⚠️ GithubBaseInfo: "
+	rawURL := fmt.Sprintf("%s/repos/%s/%s", githubAPIURL, user, repo)
+	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return GithubBaseInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	if status == http.StatusNotFound {
+		return GithubBaseInfo{}, nil // 仓库不存在 -> 降级
+	}
+	if status != http.StatusOK {
+		return GithubBaseInfo{}, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data GithubBaseInfo
+	if err := json.Unmarshal(body, &data); err != nil {
+		return GithubBaseInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	return data, nil
+}
+
+// 获取 Github 贡献者信息
+//
+// 参数:
+//   - [ctx]         上下文
+//   - [client]       共享 HTTP Client
+//   - [githubAPIURL] GitHub API 地址
+//   - [githubToken]  Github Token
+//   - [user]         用户
+//   - [repo]         仓库
+//   - [anon]         总数是否包含匿名贡献者
+//
+// 返回值:
+//   - [GithubContributorsInfo] 贡献者列表（非 Bot，按贡献排序，最多 100）
+//   - 贡献者总数（含 Bot；404/204 时为 0）
+func getGithubContributorsInfo(ctx context.Context, client *HTTPClient, githubAPIURL string, githubToken string, user string, repo string, anon bool) ([]GithubContributorsInfo, int, error) {
+	printErrTitle := "📦⚠️ GithubContributorsInfo: "
+	query := ""
+	if anon {
+		query = "&anon=1"
+	}
+	rawURL := fmt.Sprintf("%s/repos/%s/%s/contributors?page=1&per_page=100%s", githubAPIURL, user, repo, query)
+	res, err := httpGetResponse(ctx, client, rawURL, githubHeaders(githubToken))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	body, status := res.Body, res.Status
+	// 404（仓库不存在）/ 204（空仓库，无贡献者）-> 降级
+	if status == http.StatusNotFound || status == http.StatusNoContent {
+		return nil, 0, nil
+	}
+	if status != http.StatusOK {
+		return nil, 0, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	}
+	var data []GithubContributorsInfo
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, 0, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+
+	githubContributorsInfo := []GithubContributorsInfo{}
+	// 仅保留非 Bot、非匿名贡献者
+	for _, value := range data {
+		if value.Type == "User" {
+			githubContributorsInfo = append(githubContributorsInfo, value)
+		}
+	}
+
+	// 超过一页时，按每页 1 条统计准确的总数
+	total := len(data)
+	if linkLastPage(res.Header.Get("Link")) > 1 {
+		total, status, err = countGithubList(ctx, client, fmt.Sprintf("%s/repos/%s/%s/contributors?per_page=1%s", githubAPIURL, user, repo, query), githubToken)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s%w", printErrTitle, err)
+		}
+		if status != http.StatusOK {
+			return nil, 0, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+		}
+	}
+	return githubContributorsInfo, total, nil
+}
+
+// 格式化 Github 信息
+//
+// 取 [githubURL] 的 host（按原样查找，避免误匹配 githubXcom 等相似域名）之后的 user/repo 路径，
+// 并识别 monorepo 子目录：`<host>/user/repo/tree/<ref>/<path>` 与 `<host>/user/repo/blob/<ref>/<path>`，
+// <ref> 按单段处理（含 `/` 的分支名无法与路径区分）。
+//
+// 参数:
+//   - [value]     Github 链接
+//   - [githubURL] GitHub 网页地址，例如：https://github.com
+//
+// 返回值:
+//   - githubUser 信息
+//   - githubRepo 信息
+//   - ref 分支/标签（无子目录时为空）
+//   - path 子目录（无子目录时为空）
+func formatGithubInfo(value string, githubURL string) (string, string, string, string) {
+	var githubUser, githubRepo, githubRef, githubPath string
+	host := githubURL
+	if u, err := url.Parse(githubURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	i := strings.Index(value, host+"/")
+	if i < 0 {
+		return "", "", "", ""
+	}
+	// 去除 query/fragment 尾巴（如 ?tab=、#readme）
+	rest := value[i+len(host)+1:]
+	if i := strings.IndexAny(rest, "#?"); i >= 0 {
+		rest = rest[:i]
+	}
+	info := strings.Split(rest, "/")
+	if len(info) < 2 || info[0] == "" || info[1] == "" {
+		return "", "", "", ""
+	}
+	githubUser = info[0]
+	githubRepo = strings.TrimSuffix(info[1], ".git")
+	// 子目录
+	if len(info) >= 5 && (info[2] == "tree" || info[2] == "blob") && info[3] != "" {
+		githubPath = strings.Trim(strings.Join(info[4:], "/"), "/")
+		if githubPath != "" {
+			githubRef = info[3]
+		}
+	}
+	return githubUser, githubRepo, githubRef, githubPath
+}
+
+// 获取 package 在 Github 上的链接，monorepo 中指向 package 所在子目录
+//
+// 参数:
+//   - [value]     package 信息
+//   - [githubURL] GitHub 网页地址
+//
+// 返回值:
+//   - Github 链接
+func githubPackageURL(value PackageInfo, githubURL string) string {
+	githubURL += "/" + value.GithubUser + "/" + value.GithubRepo
+	if value.GithubRef != "" && value.GithubPath != "" {
+		githubURL += "/tree/" + value.GithubRef + "/" + value.GithubPath
+	}
+	return githubURL
+}
+
+// 对 [packageInfoList] 排序
+//
+// 参数:
+//   - [packageInfoList]  信息列表
+//   - [sortField]        排序字段 可选：name(default) | published | pubLikes | pubDownloads | githubStars | githubIssues | githubPullRequests | health
+//   - [sortMode]         排序方式 可选：asc(default) | desc
+func sortPackageInfo(packageInfoList []PackageInfo, sortField string, sortMode string) {
+	isDesc := sortMode == "desc"
+	sort.SliceStable(packageInfoList, func(i, j int) bool {
+		p1 := packageInfoList[i]
+		p2 := packageInfoList[j]
+		var result bool
+		switch sortField {
+		case "name":
+			// 按照 pub 名称排序
+			result = p1.Name < p2.Name
+		case "published":
+			// 按 pub 最新发布时间排序
+			result = p1.Published > p2.Published
+		case "pubLikes":
+			// 按 pub likes 排序
+			result = p1.ScoreInfo.LikeCount < p2.ScoreInfo.LikeCount
+		case "pubDownloads":
+			// 按 pub downloads 排序
+			result = p1.ScoreInfo.DownloadCount30Days < p2.ScoreInfo.DownloadCount30Days
+		case "githubStars":
+			// 按 github stars 排序
+			result = p1.GithubBaseInfo.StargazersCount < p2.GithubBaseInfo.StargazersCount
+		case "githubIssues":
+			// 按 github open issues 排序
+			result = p1.GithubOpenIssues < p2.GithubOpenIssues
+		case "githubPullRequests":
+			// 按 github open Pull requests 排序
+			result = p1.GithubOpenPullRequests < p2.GithubOpenPullRequests
+		case "health":
+			// 按健康度排序
+			result = p1.Health.Score < p2.Health.Score
+		default:
+			result = p1.Name < p2.Name
+		}
+		if isDesc {
+			return !result
+		}
+		return result
+	})
+}
+
+// package 过滤条件，例如：githubPullRequests>20
+type FilterCondition struct {
+	Field string  // 字段 pubLikes | pubDownloads | pubPoints | githubStars | githubIssues | githubPullRequests | health
+	Op    string  // 比较运算符 > | >= | < | <= | = | !=
+	Value float64 // 比较值
+}
+
+// 匹配单个过滤条件
+var filterConditionRegexp = regexp.MustCompile(`^\s*(\w+)\s*(>=|<=|!=|>|<|=)\s*(-?\d+(?:\.\d+)?)\s*$`)
+
+// 解析过滤条件
+//
+// 参数:
+//   - [value] 过滤条件（`,` 逗号分割，同时满足），例如："githubPullRequests>20,pubPoints>=140"
+//
+// 返回值:
+//   - 过滤条件列表（value 为空时为 nil）
+func parseFilter(value string) ([]FilterCondition, error) {
+	var conditions []FilterCondition
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		match := filterConditionRegexp.FindStringSubmatch(item)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter %q, want field>number", item)
+		}
+		if _, ok := filterFieldValue(PackageInfo{}, match[1]); !ok {
+			return nil, fmt.Errorf("invalid filter %q: unknown field %q", item, match[1])
+		}
+		number, _ := strconv.ParseFloat(match[3], 64)
+		conditions = append(conditions, FilterCondition{Field: match[1], Op: match[2], Value: number})
+	}
+	return conditions, nil
+}
+
+// 获取过滤字段的值
+//
+// 返回值:
+//   - 字段值
+//   - 是否为可过滤的字段
+func filterFieldValue(value PackageInfo, field string) (float64, bool) {
+	switch field {
+	case "pubLikes":
+		return value.ScoreInfo.LikeCount, true
+	case "pubDownloads":
+		return float64(value.ScoreInfo.DownloadCount30Days), true
+	case "pubPoints":
+		return value.ScoreInfo.GrantedPoints, true
+	case "githubStars":
+		return value.GithubBaseInfo.StargazersCount, true
+	case "githubIssues":
+		return float64(value.GithubOpenIssues), true
+	case "githubPullRequests":
+		return float64(value.GithubOpenPullRequests), true
+	case "health":
+		return float64(value.Health.Score), true
+	}
+	return 0, false
+}
+
+// 是否满足过滤条件
+func (condition FilterCondition) match(value PackageInfo) bool {
+	v, _ := filterFieldValue(value, condition.Field)
+	switch condition.Op {
+	case ">":
+		return v > condition.Value
+	case ">=":
+		return v >= condition.Value
+	case "<":
+		return v < condition.Value
+	case "<=":
+		return v <= condition.Value
+	case "=":
+		return v == condition.Value
+	case "!=":
+		return v != condition.Value
+	}
+	return false
+}
+
+// 过滤 package 信息
+//
+// 有过滤条件时，无法获取信息的 package 不保留
+//
+// 参数:
+//   - [packageInfoList] 信息列表
+//   - [conditions]      过滤条件（同时满足）
+//
+// 返回值:
+//   - 满足条件的信息列表（保持原有顺序）
+func filterPackageInfo(packageInfoList []PackageInfo, conditions []FilterCondition) []PackageInfo {
+	if len(conditions) == 0 {
+		return packageInfoList
+	}
+	list := []PackageInfo{}
+	for _, value := range packageInfoList {
+		if value.Code != 1 {
+			continue
+		}
+		matched := true
+		for _, condition := range conditions {
+			if !condition.match(value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// package 健康度，满分 100
+type PackageHealth struct {
+	Score   int            // 健康度 0-100
+	Reasons []HealthReason // 扣分原因（按扣分降序）
+}
+
+// 健康度扣分原因
+type HealthReason struct {
+	Factor  string  // 扣分项 points | published | issues | pullRequests | archived | discontinued | license
+	Penalty float64 // 扣分（权重 × 比例，折算为 0-100 前）
+	Value   int     // 扣分项的值：pub points、距最新发布天数、open issues / Pull requests 数量
+	Max     int     // pub points 满分
+}
+
+// 健康度扣分项
+const (
+	healthPoints       = "points"       // pub points 未满分，按缺失比例扣分
+	healthPublished    = "published"    // 最新发布距今超过 [healthStaleDays] 天，至 [healthAbandonedDays] 天扣满
+	healthIssues       = "issues"       // open issues，至 [healthMaxIssues] 个扣满
+	healthPullRequests = "pullRequests" // open Pull requests，至 [healthMaxPullRequests] 个扣满
+	healthArchived     = "archived"     // 仓库已归档
+	healthDiscontinued = "discontinued" // pub 上已停止维护
+	healthLicense      = "license"      // 缺少许可证（GitHub 与 pub 均未识别）
+
+	// healthLowScore 不是扣分项：各扣分项均未达到 [healthMinPenalty]、但健康度低于 100 时的综合原因
+	healthLowScore = "lowScore"
+)
+
+// 支持的健康度扣分项
+var healthFactors = []string{healthPoints, healthPublished, healthIssues, healthPullRequests, healthArchived, healthDiscontinued, healthLicense}
+
+// 健康度计算的阈值
+const (
+	// defaultHealthWeights 是默认的扣分项权重，健康度按权重总和折算为 0-100。
+	defaultHealthWeights  = "points=30,published=20,issues=10,pullRequests=10,archived=20,discontinued=30,license=10"
+	healthStaleDays       = 180
+	healthAbandonedDays   = 730
+	healthMaxIssues       = 50
+	healthMaxPullRequests = 20
+	// healthMinPenalty 是按比例扣分的扣分项列为原因的最低比例（避免轻微扣分淹没主要原因）。
+	healthMinPenalty = 0.25
+)
+
+// 解析健康度扣分项权重
+//
+// 参数:
+//   - [value] 权重（`,` 逗号分割），例如："points=30,archived=20"
+//
+// 返回值:
+//   - 扣分项权重（value 为空时为空）
+func parseHealthWeights(value string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, item := range removeDuplicates(strings.Split(value, ",")) {
+		factor, weight, ok := strings.Cut(item, "=")
+		factor = strings.TrimSpace(factor)
+		if !ok || !slices.Contains(healthFactors, factor) {
+			return nil, fmt.Errorf("invalid health weight %q, want factor=weight (%s)", item, strings.Join(healthFactors, " | "))
+		}
+		weightValue, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || weightValue < 0 {
+			return nil, fmt.Errorf("invalid health weight %q, want a non-negative integer", item)
+		}
+		weights[factor] = weightValue
+	}
+	return weights, nil
+}
+
+// 计算 package 健康度
+//
+// 每个扣分项按 0-1 的比例扣除其权重，健康度 = 100 × (1 - 扣分总和 / 权重总和)。
+// 无法获取的数据（无评分、未关联 GitHub 仓库）不扣分。
+//
+// 参数:
+//   - [value]   package 信息
+//   - [weights] 扣分项权重
+//   - [now]     当前时间（计算距最新发布天数）
+//
+// 返回值:
+//   - [PackageHealth] 健康度（无法获取信息的 package 为零值）
+func computeHealth(value PackageInfo, weights map[string]int, now time.Time) PackageHealth {
+	if value.Code != 1 {
+		return PackageHealth{}
+	}
+	hasGithub := value.GithubUser != "" && value.GithubRepo != ""
+	ratios := map[string]float64{}
+	reason := map[string]HealthReason{}
+	if value.ScoreInfo.MaxPoints > 0 {
+		ratios[healthPoints] = 1 - value.ScoreInfo.GrantedPoints/value.ScoreInfo.MaxPoints
+		reason[healthPoints] = HealthReason{Value: int(value.ScoreInfo.GrantedPoints), Max: int(value.ScoreInfo.MaxPoints)}
+	}
+	if published, err := time.Parse(time.RFC3339, value.Published); err == nil {
+		days := int(now.Sub(published).Hours() / 24)
+		ratios[healthPublished] = float64(days-healthStaleDays) / (healthAbandonedDays - healthStaleDays)
+		reason[healthPublished] = HealthReason{Value: days}
+	}
+	if hasGithub {
+		ratios[healthIssues] = float64(value.GithubOpenIssues) / healthMaxIssues
+		reason[healthIssues] = HealthReason{Value: value.GithubOpenIssues}
+		ratios[healthPullRequests] = float64(value.GithubOpenPullRequests) / healthMaxPullRequests
+		reason[healthPullRequests] = HealthReason{Value: value.GithubOpenPullRequests}
+		if value.GithubBaseInfo.Archived {
+			ratios[healthArchived] = 1
+		}
+	}
+	if value.Discontinued {
+		ratios[healthDiscontinued] = 1
+	}
+	if !hasLicense(value) {
+		ratios[healthLicense] = 1
+	}
+
+	health := PackageHealth{Score: 100}
+	total, penalty := 0, 0.0
+	for _, factor := range healthFactors {
+		weight := weights[factor]
+		total += weight
+		ratio := min(max(ratios[factor], 0), 1)
+		if weight == 0 || ratio == 0 {
+			continue
+		}
+		penalty += float64(weight) * ratio
+		if ratio >= healthMinPenalty {
+			r := reason[factor]
+			r.Factor, r.Penalty = factor, float64(weight)*ratio
+			health.Reasons = append(health.Reasons, r)
+		}
+	}
+	if total > 0 {
+		health.Score = int(math.Round(100 * (1 - penalty/float64(total))))
+	}
+	if len(health.Reasons) == 0 && health.Score < 100 {
+		health.Reasons = append(health.Reasons, HealthReason{Factor: healthLowScore, Penalty: penalty})
+	}
+	sort.SliceStable(health.Reasons, func(i, j int) bool {
+		return health.Reasons[i].Penalty > health.Reasons[j].Penalty
+	})
+	return health
+}
+
+// 是否有许可证（GitHub 识别的许可证或 pub 的 license 标签）
+func hasLicense(value PackageInfo) bool {
+	if value.GithubBaseInfo.License.Name != "" {
+		return true
+	}
+	for _, tag := range value.ScoreInfo.Tags {
+		if strings.HasPrefix(tag, "license:") && tag != "license:unknown" {
+			return true
+		}
+	}
+	// 未获取到任何许可证数据时（如自托管仓库且未关联 GitHub）不扣分
+	return value.GithubUser == "" && len(value.ScoreInfo.Tags) == 0
+}
+
+// 表格渲染选项
+type TableOptions struct {
+	// 排序字段（仅用于表头展示）
+	SortField string
+	// 是否将指向同一 Github 仓库（GithubUser/GithubRepo）的 package 合并为一组展示，
+	// 组头展示共享的 Github 信息，组内每个 package 仅展示 pub.dev 信息
+	GroupByRepo bool
+	// monorepo 中 package 的 Issues / Pull_requests 按 label 过滤，`{name}` 替换为 package 名称，
+	// 例如："p: {name}"。为空时不过滤
+	IssueLabel string
+	// GitHub 网页地址，用于表格中的链接（为空时为 [defaultGithubURL]）
+	GithubURL string
+	// 语言（en | zh-CN），为空时为 en
+	Locale string
+	// 渲染方式：badge（Shields 徽章，默认）| native（直接展示已获取的数据）| svg（生成的 SVG 徽章）
+	Render string
+	// svg 渲染时徽章目录相对于 Markdown 文件的路径，例如："assets/pub-dashboard"
+	BadgeURL string
+	// 是否展示仓库活跃度（已归档、最近推送、默认分支 CI 状态）
+	Activity bool
+	// 计算最近推送天数的当前时间（为零值时为 time.Now()）
+	Now time.Time
+	// 贡献者展示方式：stacked（默认）| inline | names | none（不展示该列）
+	ContributorsLayout string
+	// 展示的贡献者数量（为 0 时为 [defaultContributorsCount]）
+	ContributorsCount int
+	// 贡献者头像宽度（像素，为 0 时为 [defaultContributorsSize]）
+	ContributorsSize int
+	// 是否展示健康度列（见 [computeHealth]）
+	Health bool
+}
+
+// 贡献者展示方式
+const (
+	contributorsStacked = "stacked" // 表格排列头像：单数时首位单独一行，其余两两一行
+	contributorsInline  = "inline"  // 头像排成一行
+	contributorsNames   = "names"   // 仅展示用户名
+	contributorsNone    = "none"    // 不展示贡献者列
+)
+
+// 支持的贡献者展示方式
+var contributorsLayouts = []string{contributorsStacked, contributorsInline, contributorsNames, contributorsNone}
+
+// 贡献者展示默认值
+const (
+	defaultContributorsCount = 3
+	defaultContributorsSize  = 36
+)
+
+// 渲染方式
+const (
+	renderBadge  = "badge"  // Shields 徽章，展示时实时获取数据
+	renderNative = "native" // 直接展示已获取的数据，不依赖 Shields
+	renderSVG    = "svg"    // 使用已获取的数据生成 SVG 徽章文件，按相对路径引用
+)
+
+// 支持的渲染方式
+var renderModes = []string{renderBadge, renderNative, renderSVG}
+
+// 徽章图标（Shields logo 与 SVG 徽章共用）
+const (
+	downloadIcon = "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZpZXdCb3g9IjAgMCAyNCAyNCIgZmlsbD0icmdiYSgyNTUsMjU1LDI1NSwxKSI+PHBhdGggZmlsbD0ibm9uZSIgZD0iTTAgMGgyNHYyNEgweiI+PC9wYXRoPjxwYXRoIGQ9Ik0zIDE5SDIxVjIxSDNWMTlaTTEzIDEzLjE3MTZMMTkuMDcxMSA3LjEwMDVMMjAuNDg1MyA4LjUxNDcyTDEyIDE3TDMuNTE0NzIgOC41MTQ3Mkw0LjkyODkzIDcuMTAwNUwxMSAxMy4xNzE2VjJIMTNWMTMuMTcxNloiPjwvcGF0aD48L3N2Zz4="
+	pointIcon    = "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZpZXdCb3g9IjAgMCAyNCAyNCIgZmlsbD0icmdiYSgyNTUsMjU1LDI1NSwxKSI+PHBhdGggZmlsbD0ibm9uZSIgZD0iTTAgMGgyNHYyNEgweiI+PC9wYXRoPjxwYXRoIGQ9Ik0yMyAxMkwxNS45Mjg5IDE5LjA3MTFMMTQuNTE0NyAxNy42NTY5TDIwLjE3MTYgMTJMMTQuNTE0NyA2LjM0MzE3TDE1LjkyODkgNC45Mjg5NkwyMyAxMlpNMy44Mjg0MyAxMkw5LjQ4NTI4IDE3LjY1NjlMOC4wNzEwNyAxOS4wNzExTDEgMTJMOC4wNzEwNyA0LjkyODk2TDkuNDg1MjggNi4zNDMxN0wzLjgyODQzIDEyWiI+PC9wYXRoPjwvc3ZnPg=="
+)
+
+// 获取 svg 渲染时徽章的相对路径
+//
+// 参数:
+//   - [name] package 名称
+//   - [kind] 徽章类型 downloads | likes | points | stars
+func (options TableOptions) badgeURL(name string, kind string) string {
+	return path.Join(options.BadgeURL, badgeFilename(name, kind, options.Locale))
+}
+
+// 获取表格使用的语言包
+func (options TableOptions) locale() Locale {
+	return getLocale(options.Locale)
+}
+
+// 获取展示的贡献者数量
+func (options TableOptions) contributorsCount() int {
+	if options.ContributorsCount <= 0 {
+		return defaultContributorsCount
+	}
+	return options.ContributorsCount
+}
+
+// 获取贡献者头像宽度
+func (options TableOptions) contributorsSize() int {
+	if options.ContributorsSize <= 0 {
+		return defaultContributorsSize
+	}
+	return options.ContributorsSize
+}
+
+// 获取贡献者列（不展示贡献者列时为空）
+func (options TableOptions) contributorsColumn(cell string) string {
+	if options.ContributorsLayout == contributorsNone {
+		return ""
+	}
+	return " | " + cell
+}
+
+// 获取健康度列（未开启时为空）
+func (options TableOptions) healthColumn(cell string) string {
+	if !options.Health {
+		return ""
+	}
+	return " | " + cell
+}
+
+// 获取计算最近推送天数的当前时间
+func (options TableOptions) now() time.Time {
+	if options.Now.IsZero() {
+		return time.Now()
+	}
+	return options.Now
+}
+
+// 语言包：表格文案与数字、日期格式
+type Locale struct {
+	Summary            string            // 表头摘要，参数：排序字段、总数
+	SortFields         map[string]string // 排序字段名称（未配置时使用原值）
+	Package            string            // 列名
+	StarsLikes         string            // 列名
+	DownloadsPoints    string            // 列名
+	IssuesPullRequests string            // 列名
+	Contributors       string            // 列名
+	License            string            // 标签
+	Platform           string            // 标签
+	Published          string            // 标签
+	Packages           string            // 分组的 package 数量标签
+	PullRequests       string            // native 渲染时无数量的 Pull requests 链接文字
+	Issues             string            // native 渲染时无数量的 Issues 链接文字
+	ContributorsTotal  string            // 贡献者总数标签
+	Archived           string            // 已归档标签
+	LastPush           string            // 最近推送标签
+	Today              string            // 最近推送：当天
+	DayAgo             string            // 最近推送：1 天前
+	DaysAgo            string            // 最近推送：N 天前，参数：天数
+	NoTag              string            // pub 版本缺少对应的 git tag
+	Contributor        string            // 贡献者排行列名
+	Contributions      string            // 贡献者排行列名
+	PackagesCount      string            // 贡献者排行头像提示，参数：package 数量
+	LatestRelease      string            // 最新 GitHub release 标签
+	Health             string            // 健康度列名
+	HealthReasons      map[string]string // 健康度扣分原因（按扣分项，参数见 [HealthReason]）
+	PerMonth           string            // 下载量单位
+	Updated            string            // 更新时间页脚，参数：更新时间
+	UpdatedLayout      string            // 更新时间格式
+	PublishedLayout    string            // 发布时间格式，为空时原样展示
+	CountUnits         []CountUnit       // 数量缩写单位（从大到小）
+}
+
+// 数量缩写单位
+type CountUnit struct {
+	Value  int
+	Suffix string
+}
+
+// 默认语言
+const defaultLocale = "en"
+
+// 内置语言包
+var locales = map[string]Locale{
+	"en": {
+		Summary:            "Sort by %s | Total %d",
+		Package:            "Package",
+		StarsLikes:         "Stars/Likes",
+		DownloadsPoints:    "Downloads/Points",
+		IssuesPullRequests: "Issues / Pull_requests",
+		Contributors:       "Contributors",
+		License:            "License",
+		Platform:           "Platform",
+		Published:          "Published",
+		Packages:           "Packages",
+		Issues:             "Issues",
+		PullRequests:       "Pull requests",
+		ContributorsTotal:  "Total",
+		Archived:           "Archived",
+		LastPush:           "Last push",
+		Today:              "today",
+		DayAgo:             "1 day ago",
+		DaysAgo:            "%d days ago",
+		NoTag:              "no git tag",
+		Contributor:        "Contributor",
+		Contributions:      "Commits",
+		PackagesCount:      "%d packages",
+		LatestRelease:      "latest release",
+		Health:             "Health",
+		PerMonth:           "month",
+		Updated:            "Updated on %s by [Action](https://github.com/AmosHuKe/pub-dashboard).",
+		UpdatedLayout:      time.RFC3339,
+		CountUnits:         []CountUnit{{1000000, "M"}, {1000, "k"}},
+		HealthReasons: map[string]string{
+			healthPoints:       "pub points %d/%d",
+			healthPublished:    "published %d days ago",
+			healthIssues:       "%d open issues",
+			healthPullRequests: "%d open pull requests",
+			healthArchived:     "archived repo",
+			healthDiscontinued: "discontinued",
+			healthLicense:      "no license",
+			healthLowScore:     "low overall score",
+		},
+	},
+	"zh-CN": {
+		Summary: "排序：%s | 共 %d 个",
+		SortFields: map[string]string{
+			"name":               "名称",
+			"published":          "发布时间",
+			"pubLikes":           "点赞数",
+			"pubDownloads":       "下载量",
+			"githubStars":        "Star 数",
+			"githubIssues":       "Issues 数",
+			"githubPullRequests": "Pull requests 数",
+			"health":             "健康度",
+		},
+		Package:            "Package",
+		StarsLikes:         "Star/点赞",
+		DownloadsPoints:    "下载量/评分",
+		IssuesPullRequests: "Issues / Pull requests",
+		Contributors:       "贡献者",
+		License:            "许可证",
+		Platform:           "平台",
+		Published:          "发布时间",
+		Packages:           "Package 数量",
+		Issues:             "Issues",
+		PullRequests:       "Pull requests",
+		ContributorsTotal:  "共",
+		Archived:           "已归档",
+		LastPush:           "最近推送",
+		Today:              "今天",
+		DayAgo:             "1 天前",
+		DaysAgo:            "%d 天前",
+		NoTag:              "缺少 git tag",
+		Contributor:        "贡献者",
+		Contributions:      "提交数",
+		PackagesCount:      "%d 个 package",
+		LatestRelease:      "最新 release",
+		Health:             "健康度",
+		PerMonth:           "月",
+		Updated:            "由 [Action](https://github.com/AmosHuKe/pub-dashboard) 更新于 %s。",
+		UpdatedLayout:      "2006-01-02 15:04:05 (UTC-07:00)",
+		PublishedLayout:    "2006-01-02 15:04",
+		CountUnits:         []CountUnit{{100000000, "亿"}, {10000, "万"}},
+		HealthReasons: map[string]string{
+			healthPoints:       "pub 评分 %d/%d",
+			healthPublished:    "%d 天未发布",
+			healthIssues:       "%d 个 open issues",
+			healthPullRequests: "%d 个 open Pull requests",
+			healthArchived:     "仓库已归档",
+			healthDiscontinued: "已停止维护",
+			healthLicense:      "缺少许可证",
+			healthLowScore:     "综合得分偏低",
+		},
+	},
+}
+
+// 获取语言包（未知语言时为 [defaultLocale]）
+func getLocale(name string) Locale {
+	if locale, ok := locales[name]; ok {
+		return locale
+	}
+	return locales[defaultLocale]
+}
+
+// 格式化数量（按语言缩写，例如：en "1.5k"，zh-CN "1.5万"）
+func (l Locale) formatCount(num int) string {
+	for _, unit := range l.CountUnits {
+		if num >= unit.Value {
+			formatted := fmt.Sprintf("%.2f", float64(num)/float64(unit.Value))
+			// 去掉多余的0和小数点
+			formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+			return formatted + unit.Suffix
+		}
+	}
+	return strconv.Itoa(num)
+}
+
+// 格式化发布时间（无法解析时原样展示）
+func (l Locale) formatPublished(value string) string {
+	if l.PublishedLayout == "" {
+		return value
+	}
+	published, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return published.UTC().Format(l.PublishedLayout)
+}
+
+// 格式化距今天数（例如：en "3 days ago"，zh-CN "3 天前"）
+//
+// 参数:
+//   - [value] 时间（RFC3339）
+//   - [now]   当前时间
+//
+// 返回值:
+//   - 距今天数（无法解析时为空）
+func (l Locale) formatDaysAgo(value string, now time.Time) string {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ""
+	}
+	switch days := int(now.Sub(date).Hours() / 24); {
+	case days <= 0:
+		return l.Today
+	case days == 1:
+		return l.DayAgo
+	default:
+		return fmt.Sprintf(l.DaysAgo, days)
+	}
+}
+
+// 格式化表头的排序字段
+func (l Locale) formatSortField(sortField string) string {
+	if name, ok := l.SortFields[sortField]; ok {
+		return name
+	}
+	return sortField
+}
+
+// 获取表格中使用的 GitHub 网页地址
+func (options TableOptions) githubURL() string {
+	if options.GithubURL == "" {
+		return defaultGithubURL
+	}
+	return options.GithubURL
+}
+
+// 获取 package 在 pub 仓库的网页地址
+func pubPackageURL(value PackageInfo) string {
+	pubURL := value.PubURL
+	if pubURL == "" {
+		pubURL = defaultPubURL
+	}
+	return pubURL + "/packages/" + value.Name
+}
+
+// 组装表格内容
+//
+// 参数:
+//   - [packageInfoList]  信息列表
+//   - [options]          渲染选项
+//
+// 返回值:
+//   - markdown 表格内容
+func assembleMarkdownTable(packageInfoList []PackageInfo, options TableOptions) string {
+	locale := options.locale()
+	separator := "|--------------------|------------------------|------------------------------|-----------------------------------|"
+	if options.Health {
+		separator += ":------:|"
+	}
+	if options.ContributorsLayout != contributorsNone {
+		separator += ":-----------------------:|"
+	}
+	markdown := ""
+	markdown += "<sub>" + fmt.Sprintf(locale.Summary, locale.formatSortField(options.SortField), len(packageInfoList)) + "</sub> \n\n" +
+		"| <sub>" + locale.Package + "</sub> | <sub>" + locale.StarsLikes + "</sub> | <sub>" + locale.DownloadsPoints + "</sub> | <sub>" + locale.IssuesPullRequests + "</sub>" + options.healthColumn("<sub>"+locale.Health+"</sub>") + options.contributorsColumn("<sub>"+locale.Contributors+"</sub>") + " | \n" +
+		separator + " \n"
+	if !options.GroupByRepo {
+		for _, value := range packageInfoList {
+			markdown += formatMarkdownTableRow(assembleMarkdownTableRow(value, options), options)
+		}
+		return markdown
+	}
+	for _, group := range groupPackageInfoByRepo(packageInfoList) {
+		// 仓库下仅有一个 package 时无需分组
+		if len(group) == 1 {
+			markdown += formatMarkdownTableRow(assembleMarkdownTableRow(group[0], options), options)
+			continue
+		}
+		// 组头展示整个仓库的信息，不限定子目录
+		repo := group[0]
+		repo.GithubRef, repo.GithubPath = "", ""
+		markdown += formatMarkdownTableGroupRow(repo, assembleMarkdownTableRow(repo, options), len(group), options)
+		for _, value := range group {
+			markdown += formatMarkdownTableSubRow(assembleMarkdownTableRow(value, options), isIssueLabelScoped(value, options), options)
+		}
+	}
+	return markdown
+}
+
+// 组装单个 package 的表格展示信息
+//
+// 参数:
+//   - [value]   package 信息
+//   - [options] 渲染选项
+//
+// 返回值:
+//   - [MarkdownTable] 展示信息
+func assembleMarkdownTableRow(value PackageInfo, options TableOptions) MarkdownTable {
+	var name, version, platform, licenseName, published,
+		githubStars, pubLikes, pubPoints, pubDownloadCount30Days,
+		issues, pullRequests, contributors, activity, release, health string
+	switch value.Code {
+	case 0:
+		// 无法获取信息
+		name = value.Name + " ⁉️"
+	case 1:
+		// 已获取信息
+		locale := options.locale()
+		name = "[" + value.Name + "](" + pubPackageURL(value) + ")"
+		version = "v" + value.Version
+		platform = "<strong>" + locale.Platform + ":</strong> "
+		if len(value.ScoreInfo.TagsPlatform) > 0 {
+			platform += strings.Join(value.ScoreInfo.TagsPlatform, ", ")
+		} else {
+			platform += "-"
+		}
+		published = "<strong>" + locale.Published + ":</strong> " + locale.formatPublished(value.Published)
+		githubStars = ""
+		pubLikes = "[![Pub likes](https://img.shields.io/pub/likes/" + value.Name + "?style=social&logo=flutter&logoColor=168AFD&label=)](" + pubPackageURL(value) + ")"
+		pubPoints = "[![Pub points](https://img.shields.io/pub/points/" + value.Name + "?style=flat&label=&logo=" + pointIcon + ")](" + pubPackageURL(value) + "/score)"
+		pubDownloadCount30Days = "[![Pub downloads](https://img.shields.io/badge/" + url.PathEscape(locale.formatCount(value.ScoreInfo.DownloadCount30Days)+"/"+locale.PerMonth) + "-4AC51C?style=flat&logo=" + downloadIcon + ")](" + pubPackageURL(value) + ")"
+		// native 渲染或自托管 pub 仓库（Shields 无法获取）：直接展示已获取的数据
+		native := options.Render == renderNative
+		if options.Render == renderSVG {
+			pubLikes, pubPoints, pubDownloadCount30Days = "-", "-", "-"
+			if value.ScoreInfo.MaxPoints > 0 {
+				pubLikes = "[![Pub likes](" + options.badgeURL(value.Name, "likes") + ")](" + pubPackageURL(value) + ")"
+				pubPoints = "[![Pub points](" + options.badgeURL(value.Name, "points") + ")](" + pubPackageURL(value) + "/score)"
+				pubDownloadCount30Days = "[![Pub downloads](" + options.badgeURL(value.Name, "downloads") + ")](" + pubPackageURL(value) + ")"
+			}
+		} else if native || value.Hosted {
+			pubLikes, pubPoints, pubDownloadCount30Days = "-", "-", "-"
+			if value.ScoreInfo.MaxPoints > 0 {
+				pubLikes = "[👍 " + locale.formatCount(int(value.ScoreInfo.LikeCount)) + "](" + pubPackageURL(value) + ")"
+				pubPoints = "[" + strconv.Itoa(int(value.ScoreInfo.GrantedPoints)) + "/" + strconv.Itoa(int(value.ScoreInfo.MaxPoints)) + "](" + pubPackageURL(value) + "/score)"
+				pubDownloadCount30Days = "[" + locale.formatCount(value.ScoreInfo.DownloadCount30Days) + "/" + locale.PerMonth + "](" + pubPackageURL(value) + ")"
+			}
+		}
+		issues = "-"
+		pullRequests = "-"
+		if options.Health {
+			health = formatHealth(value.Health, locale)
+		}
+
+		// Github
+		if value.GithubUser != "" && value.GithubRepo != "" {
+			githubURL := value.GithubUser + "/" + value.GithubRepo
+			licenseName = "<strong>" + locale.License + ":</strong> "
+			if value.GithubBaseInfo.License.Name != "" {
+				licenseName += value.GithubBaseInfo.License.Name
+			} else {
+				licenseName += "-"
+			}
+			if options.Activity {
+				activity = formatActivity(value, options)
+			}
+			if reasons := releaseMismatches(value, locale); len(reasons) > 0 {
+				release = "🏷️ " + strings.Join(reasons, " · ")
+			}
+			githubStars = "[![GitHub stars](https://img.shields.io/github/stars/" + githubURL + "?style=social&logo=github&logoColor=1F2328&label=)](" + githubPackageURL(value, options.githubURL()) + ")"
+			if native {
+				githubStars = "[⭐ " + locale.formatCount(int(value.GithubBaseInfo.StargazersCount)) + "](" + githubPackageURL(value, options.githubURL()) + ")"
+			}
+			if options.Render == renderSVG {
+				githubStars = "[![GitHub stars](" + options.badgeURL(value.Name, "stars") + ")](" + githubPackageURL(value, options.githubURL()) + ")"
+			}
+			if isIssueLabelScoped(value, options) {
+				// 按 package label 过滤
+				label := strings.ReplaceAll(options.IssueLabel, "{name}", value.Name)
+				issuesQuery := url.QueryEscape(`is:issue is:open label:"` + label + `"`)
+				pullRequestsQuery := url.QueryEscape(`is:pr is:open label:"` + label + `"`)
+				issues = "[![GitHub issues](https://img.shields.io/github/issues/" + githubURL + "/" + url.PathEscape(label) + "?label=)](" + options.githubURL() + "/" + githubURL + "/issues?q=" + issuesQuery + ")"
+				pullRequests = "[![GitHub pull requests](https://img.shields.io/github/issues-pr/" + githubURL + "/" + url.PathEscape(label) + "?label=)](" + options.githubURL() + "/" + githubURL + "/pulls?q=" + pullRequestsQuery + ")"
+				counts, counted := value.GithubLabelCounts[label]
+				if native && counted {
+					issues = "[🐞 " + locale.formatCount(counts.Issues) + "](" + options.githubURL() + "/" + githubURL + "/issues?q=" + issuesQuery + ")"
+					pullRequests = "[🔀 " + locale.formatCount(counts.PullRequests) + "](" + options.githubURL() + "/" + githubURL + "/pulls?q=" + pullRequestsQuery + ")"
+				} else if native {
+					// 未获取按 label 过滤的数量，仅展示链接
+					issues = "[" + locale.Issues + "](" + options.githubURL() + "/" + githubURL + "/issues?q=" + issuesQuery + ")"
+					pullRequests = "[" + locale.PullRequests + "](" + options.githubURL() + "/" + githubURL + "/pulls?q=" + pullRequestsQuery + ")"
+				} else if options.Render == renderSVG && counted {
+					issues = "[![GitHub issues](" + options.badgeURL(value.Name, "issues-label") + ")](" + options.githubURL() + "/" + githubURL + "/issues?q=" + issuesQuery + ")"
+					pullRequests = "[![GitHub pull requests](" + options.badgeURL(value.Name, "pulls-label") + ")](" + options.githubURL() + "/" + githubURL + "/pulls?q=" + pullRequestsQuery + ")"
+				}
+			} else if native {
+				issues = "[🐞 " + locale.formatCount(value.GithubOpenIssues) + "](" + options.githubURL() + "/" + githubURL + "/issues)"
+				pullRequests = "[🔀 " + locale.formatCount(value.GithubOpenPullRequests) + "](" + options.githubURL() + "/" + githubURL + "/pulls)"
+			} else if options.Render == renderSVG {
+				issues = "[![GitHub issues](" + options.badgeURL(value.Name, "issues") + ")](" + options.githubURL() + "/" + githubURL + "/issues)"
+				pullRequests = "[![GitHub pull requests](" + options.badgeURL(value.Name, "pulls") + ")](" + options.githubURL() + "/" + githubURL + "/pulls)"
+			} else {
+				issues = "[![GitHub issues](https://img.shields.io/github/issues/" + githubURL + "?label=)](" + options.githubURL() + "/" + githubURL + "/issues)"
+				pullRequests = "[![GitHub pull requests](https://img.shields.io/github/issues-pr/" + githubURL + "?label=)](" + options.githubURL() + "/" + githubURL + "/pulls)"
+			}
+
+			if len(value.GithubContributorsInfo) > 0 {
+				contributors = formatContributors(value.GithubContributorsInfo, value.GithubBaseInfo.ContributorsTotal, options.githubURL()+"/"+githubURL+"/graphs/contributors", options)
+			}
+		}
+	}
+	return MarkdownTable{
+		Name:                   name,
+		Version:                version,
+		Description:            value.Description,
+		LicenseName:            licenseName,
+		Platform:               platform,
+		Published:              published,
+		GithubStars:            githubStars,
+		PubLikes:               pubLikes,
+		PubPoints:              pubPoints,
+		PubDownloadCount30Days: pubDownloadCount30Days,
+		Issues:                 issues,
+		PullRequests:           pullRequests,
+		Contributors:           contributors,
+		Activity:               activity,
+		Release:                release,
+		Health:                 health,
+	}
+}
+
+// 格式化贡献者单元格
+//
+// 参数:
+//   - [list]    贡献者列表（按贡献排序）
+//   - [total]   贡献者总数
+//   - [link]    贡献者页面地址
+//   - [options] 渲染选项（展示方式、数量、头像宽度）
+//
+// 返回值:
+//   - 贡献者单元格内容
+func formatContributors(list []GithubContributorsInfo, total int, link string, options TableOptions) string {
+	list = list[:min(options.contributorsCount(), len(list))]
+	size := options.contributorsSize()
+	totalLink := `<a href="` + link + `">` + options.locale().ContributorsTotal + `: ` + strconv.Itoa(total) + `</a>`
+	avatar := func(contributor GithubContributorsInfo, width int) string {
+		return `<a href="` + contributor.HtmlUrl + `"><img width="` + strconv.Itoa(width) + `px" src="` + getGithubAvatarUrl(contributor.Id) + `" /></a>`
+	}
+
+	switch options.ContributorsLayout {
+	case contributorsInline:
+		avatars := make([]string, len(list))
+		for i, contributor := range list {
+			avatars[i] = avatar(contributor, size)
+		}
+		return strings.Join(avatars, " ") + " <br/> " + totalLink
+	case contributorsNames:
+		names := make([]string, len(list))
+		for i, contributor := range list {
+			names[i] = "[@" + contributor.Login + "](" + contributor.HtmlUrl + ")"
+		}
+		return strings.Join(names, ", ") + " <br/> " + totalLink
+	}
+
+	// stacked：单数时首位单独一行（较大头像），其余两两一行
+	contributors := `<table align="center" border="0">`
+	rest := list
+	if len(list)%2 == 1 {
+		contributors += `<tr align="center">`
+		if len(list) > 1 {
+			contributors += `<td colspan="2">`
+		} else {
+			contributors += `<td>`
+		}
+		contributors += avatar(list[0], size)
+		contributors += `</td>`
+		contributors += `</tr>`
+		rest = list[1:]
+	}
+	for i := 0; i+1 < len(rest); i += 2 {
+		contributors += `<tr align="center">`
+		contributors += `<td>` + avatar(rest[i], size*5/6) + `</td>`
+		contributors += `<td>` + avatar(rest[i+1], size*5/6) + `</td>`
+		contributors += `</tr>`
+	}
+
+	// total
+	contributors += `<tr align="center">`
+	contributors += `<td colspan="2">` + totalLink + `</td>`
+	contributors += `</tr>`
+	contributors += `</table>`
+	return contributors
+}
+
+// 排除贡献者（GitHub 标记为 User 的机器人账号等），同时从贡献者总数中扣除
+//
+// 参数:
+//   - [packageInfoList] package 信息列表（原地修改）
+//   - [patterns]        用户名列表，支持通配符 `*`，不区分大小写，例如：["renovate-bot", "*-bot"]
+func excludeContributors(packageInfoList []PackageInfo, patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	excluded := func(login string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(login)); matched {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range packageInfoList {
+		value := &packageInfoList[i]
+		before := len(value.GithubContributorsInfo)
+		value.GithubContributorsInfo = slices.DeleteFunc(value.GithubContributorsInfo, func(contributor GithubContributorsInfo) bool {
+			return excluded(contributor.Login)
+		})
+		value.GithubBaseInfo.ContributorsTotal = max(value.GithubBaseInfo.ContributorsTotal-(before-len(value.GithubContributorsInfo)), 0)
+	}
+}
+
+// CI 状态图标
+var ciStatusIcons = map[string]string{
+	ciSuccess: "✅",
+	ciFailure: "❌",
+	ciPending: "🟡",
+}
+
+// 格式化仓库活跃度：已归档、最近推送、默认分支 CI 状态
+//
+// 参数:
+//   - [value]   package 信息
+//   - [options] 渲染选项
+//
+// 返回值:
+//   - 活跃度，例如："<strong>Last push:</strong> 3 days ago · <strong>CI:</strong> [✅](...)"
+func formatActivity(value PackageInfo, options TableOptions) string {
+	locale := options.locale()
+	githubURL := options.githubURL() + "/" + value.GithubUser + "/" + value.GithubRepo
+	parts := []string{}
+	if value.GithubBaseInfo.Archived {
+		parts = append(parts, "🗄️ <strong>"+locale.Archived+"</strong>")
+	}
+	if pushed := locale.formatDaysAgo(value.GithubBaseInfo.PushedAt, options.now()); pushed != "" {
+		parts = append(parts, "<strong>"+locale.LastPush+":</strong> "+pushed)
+	}
+	if icon, ok := ciStatusIcons[value.GithubCIStatus]; ok {
+		parts = append(parts, "<strong>CI:</strong> ["+icon+"]("+githubURL+"/commits/"+url.PathEscape(value.GithubBaseInfo.DefaultBranch)+")")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// 健康度图标（从高到低，健康度不低于 Min 时使用）
+var healthIcons = []struct {
+	Min  int
+	Icon string
+}{{80, "🟢"}, {50, "🟡"}, {0, "🔴"}}
+
+// 格式化健康度单元格
+//
+// 参数:
+//   - [health] 健康度
+//   - [locale] 语言包
+//
+// 返回值:
+//   - 健康度，例如："🟡 62 <br/> <sub>archived repo · 35 open issues</sub>"
+func formatHealth(health PackageHealth, locale Locale) string {
+	cell := formatHealthScore(health.Score)
+	if len(health.Reasons) > 0 {
+		cell += formatExtraLine(strings.Join(formatHealthReasons(health.Reasons, locale), " · "))
+	}
+	return cell
+}
+
+// 格式化健康度分数，例如："🟢 92"
+func formatHealthScore(score int) string {
+	for _, level := range healthIcons {
+		if score >= level.Min {
+			return level.Icon + " " + strconv.Itoa(score)
+		}
+	}
+	return healthIcons[len(healthIcons)-1].Icon + " " + strconv.Itoa(score)
+}
+
+// 格式化健康度扣分原因，例如：["pub points 120/160", "35 open issues"]
+func formatHealthReasons(reasons []HealthReason, locale Locale) []string {
+	result := make([]string, len(reasons))
+	for i, reason := range reasons {
+		format := locale.HealthReasons[reason.Factor]
+		switch reason.Factor {
+		case healthPoints:
+			result[i] = fmt.Sprintf(format, reason.Value, reason.Max)
+		case healthPublished, healthIssues, healthPullRequests:
+			result[i] = fmt.Sprintf(format, reason.Value)
+		default:
+			result[i] = format
+		}
+	}
+	return result
+}
+
+// 是否按 label 过滤 package 的 Issues / Pull_requests（仅 monorepo 子目录中的 package）
+func isIssueLabelScoped(value PackageInfo, options TableOptions) bool {
+	return options.IssueLabel != "" && value.GithubPath != ""
+}
+
+// 按 Github 仓库分组（保持排序后的顺序）
+//
+// 分组按仓库首次出现的位置排列，组内保持原有顺序；
+// 未关联 Github 仓库的 package 各自成组。
+//
+// 参数:
+//   - [packageInfoList] 信息列表（已排序）
+//
+// 返回值:
+//   - 分组后的信息列表
+func groupPackageInfoByRepo(packageInfoList []PackageInfo) [][]PackageInfo {
+	groups := [][]PackageInfo{}
+	groupIndex := map[string]int{}
+	for _, value := range packageInfoList {
+		if value.GithubUser == "" || value.GithubRepo == "" {
+			groups = append(groups, []PackageInfo{value})
+			continue
+		}
+		// Github 用户名与仓库名大小写不敏感
+		key := strings.ToLower(value.GithubUser + "/" + value.GithubRepo)
+		if i, ok := groupIndex[key]; ok {
+			groups[i] = append(groups[i], value)
+			continue
+		}
+		groupIndex[key] = len(groups)
+		groups = append(groups, []PackageInfo{value})
+	}
+	return groups
+}
+
+// 格式化普通表格行
+func formatMarkdownTableRow(value MarkdownTable, options TableOptions) string {
+	return "" +
+		"| " + value.Name + " <sup><strong>" + value.Version + "</strong></sup> <br/> <sub>" + formatString(value.Description) + "</sub> <br/> <sub>" + value.LicenseName + "</sub> <br/> <sub>" + value.Platform + "</sub> <br/> " + "<sub>" + value.Published + "</sub>" + formatExtraLine(value.Activity) + formatExtraLine(value.Release) +
+		" | " + value.GithubStars + " <br/> " + value.PubLikes +
+		" | " + value.PubDownloadCount30Days + " <br/> " + value.PubPoints +
+		" | " + value.Issues + " <br/> " + value.PullRequests +
+		options.healthColumn(value.Health) +
+		options.contributorsColumn(value.Contributors) +
+		" | \n"
+}
+
+// 格式化单元格中的附加行：活跃度、版本不一致等（为空时不展示）
+func formatExtraLine(line string) string {
+	if line == "" {
+		return ""
+	}
+	return " <br/> <sub>" + line + "</sub>"
+}
+
+// 格式化分组的组头行（展示仓库共享的 Github 信息）
+//
+// 参数:
+//   - [first]   组内第一个 package 信息（提供仓库信息）
+//   - [value]   组内第一个 package 的展示信息
+//   - [total]   组内 package 数量
+//   - [options] 渲染选项
+func formatMarkdownTableGroupRow(first PackageInfo, value MarkdownTable, total int, options TableOptions) string {
+	githubURL := first.GithubUser + "/" + first.GithubRepo
+	return "" +
+		"| 📁 [" + githubURL + "](" + githubPackageURL(first, options.githubURL()) + ") <br/> <sub>" + value.LicenseName + "</sub> <br/> <sub><strong>" + options.locale().Packages + ":</strong> " + strconv.Itoa(total) + "</sub>" + formatExtraLine(value.Activity) +
+		" | " + value.GithubStars +
+		" | " +
+		" | " + value.Issues + " <br/> " + value.PullRequests +
+		options.healthColumn("") +
+		options.contributorsColumn(value.Contributors) +
+		" | \n"
+}
+
+// 格式化分组内的 package 子行（仅展示 pub.dev 信息）
+//
+// 参数:
+//   - [value]        展示信息
+//   - [issuesScoped] Issues / Pull_requests 是否已按 package label 过滤（过滤后才在子行展示）
+//   - [options]      渲染选项
+func formatMarkdownTableSubRow(value MarkdownTable, issuesScoped bool, options TableOptions) string {
+	issues := ""
+	if issuesScoped {
+		issues = value.Issues + " <br/> " + value.PullRequests
+	}
+	return "" +
+		"| ↳ " + value.Name + " <sup><strong>" + value.Version + "</strong></sup> <br/> <sub>" + formatString(value.Description) + "</sub> <br/> <sub>" + value.Platform + "</sub> <br/> " + "<sub>" + value.Published + "</sub>" + formatExtraLine(value.Release) +
+		" | " + value.PubLikes +
+		" | " + value.PubDownloadCount30Days + " <br/> " + value.PubPoints +
+		" | " + issues +
+		options.healthColumn(value.Health) +
+		options.contributorsColumn("") +
+		" | \n"
+}
+
+// 更新 Markdown 文件（一次读取，替换所有区块后一次写入）
+//
+// 写入时先写临时文件再重命名，保留原文件权限、BOM 与换行风格（LF / CRLF）
+//
+// 参数:
+//   - [filename] 更新的文件（符号链接时更新其指向的文件）
+//   - [blocks]   区块内容
+//   - [options]  更新选项
+//
+// 返回值:
+//   - 文件内容是否有变化
+func updateMarkdown(filename string, blocks MarkdownBlocks, options UpdateOptions) (bool, error) {
+	mode := options.Mode
+	path, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return false, fmt.Errorf("📄❌ updateMarkdown: Error reade a file: %w", err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("📄❌ updateMarkdown: Error reade a file: %w", err)
+	}
+	md, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("📄❌ updateMarkdown: Error reade a file: %w", err)
+	}
+
+	newMd, diagnostics := replaceMarkdownBlocks(md, blocks, time.Now())
+	failed := 0
+	for _, diagnostic := range diagnostics {
+		location := filename
+		if diagnostic.Line > 0 {
+			location += ":" + strconv.Itoa(diagnostic.Line)
+		}
+		if diagnostic.Warning && !options.Strict {
+			fmt.Printf("📄⚠️ %s: %s\n", location, diagnostic.Message)
+			continue
+		}
+		fmt.Printf("📄❌ %s: %s\n", location, diagnostic.Message)
+		failed++
+	}
+	if failed > 0 {
+		return false, fmt.Errorf("📄❌ updateMarkdown: %s: %d invalid marker(s)", filename, failed)
+	}
+
+	changed := !bytes.Equal(md, newMd)
+	if !changed {
+		fmt.Println("📄✅ updateMarkdown: No changes")
+		return false, nil
+	}
+	if mode != UpdateModeWrite {
+		fmt.Print(unifiedDiff(filename, md, newMd))
+		fmt.Printf("📄✅ updateMarkdown: %s, changed: true\n", mode)
+		return true, nil
+	}
+
+	if err := writeFileAtomic(path, newMd, stat.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("📄❌ updateMarkdown: Error writing a file: %w", err)
+	}
+	fmt.Println("📄✅ updateMarkdown: Success")
+	return true, nil
+}
+
+// 替换 Markdown 文件中的所有区块
+//
+// 参数:
+//   - [md]     原文件内容
+//   - [blocks] 区块内容
+//   - [now]    更新时间
+//
+// 返回值:
+//   - 替换后的文件内容
+//   - 标记诊断信息
+func replaceMarkdownBlocks(md []byte, blocks MarkdownBlocks, now time.Time) ([]byte, []MarkerDiagnostic) {
+	found, diagnostics := scanMarkdownMarkers(md)
+	// 需要更新表格时，缺失表格区块视为警告
+	if len(blocks.Markers) == 0 || slices.Contains(blocks.Markers, markerTable) {
+		if !slices.ContainsFunc(found, func(block markdownBlock) bool { return block.Name == markerTable }) {
+			diagnostics = append(diagnostics, MarkerDiagnostic{Message: fmt.Sprintf("no %q marker found", markerTable), Warning: true})
+		}
+	}
+	newline := detectNewline(md)
+	newMd := bytes.NewBuffer(nil)
+	last := 0
+	for _, block := range found {
+		if len(blocks.Markers) > 0 && !slices.Contains(blocks.Markers, block.Name) {
+			continue
+		}
+		content := renderMarkdownBlock(block.Name, blocks, now)
+		if newline != "\n" {
+			content = strings.ReplaceAll(content, "\n", newline)
+		}
+		// 除更新时间外内容未变化时保留原区块，避免仅因时间戳变化而产生提交
+		oldContent := md[block.Start:block.End]
+		if block.Name == markerTable && bytes.Equal(stripUpdatedTime(oldContent), stripUpdatedTime([]byte(content))) {
+			content = string(oldContent)
+		}
+		newMd.Write(md[last:block.Start])
+		newMd.WriteString(content)
+		last = block.End
+	}
+	newMd.Write(md[last:])
+	return newMd.Bytes(), diagnostics
+}
+
+// 检测文件的换行风格（CRLF 行多于 LF 行时为 CRLF）
+func detectNewline(md []byte) string {
+	crlf := bytes.Count(md, []byte("\r\n"))
+	if crlf > 0 && crlf >= bytes.Count(md, []byte("\n"))-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// 生成区块内容（begin 与 end 标记之间）
+//
+// 参数:
+//   - [name]   区块名称
+//   - [blocks] 区块内容
+//   - [now]    更新时间
+func renderMarkdownBlock(name string, blocks MarkdownBlocks, now time.Time) string {
+	switch name {
+	case markerTable:
+		locale := getLocale(blocks.Locale)
+		return " \n" + blocks.Table + " \n" +
+			fmt.Sprintf(locale.Updated, now.Format(locale.UpdatedLayout)) + " \n"
+	case markerTotal:
+		return strconv.Itoa(blocks.Total)
+	case markerDownloads:
+		return getLocale(blocks.Locale).formatCount(blocks.Stats.Downloads)
+	case markerLikes:
+		return strconv.Itoa(blocks.Stats.Likes)
+	case markerStars:
+		return strconv.Itoa(blocks.Stats.Stars)
+	case markerPoints:
+		return strconv.Itoa(blocks.Stats.Points)
+	case markerPlatforms:
+		return formatPlatformStats(blocks.Stats.Platforms)
+	case markerContributors:
+		return strconv.Itoa(blocks.Stats.Contributors)
+	case markerLeaderboard:
+		return formatLeaderboard(blocks.Stats.Leaderboard, blocks.Leaderboard, getLocale(blocks.Locale))
+	case markerReleases:
+		return formatReleaseStats(blocks.Stats.Releases, getLocale(blocks.Locale))
+	case markerAttention:
+		return formatAttention(blocks.Stats.Attention, blocks.Attention, getLocale(blocks.Locale))
+	}
+	return ""
+}
+
+// 格式化 pub 版本与 GitHub 不一致的 package，例如："foo v1.2.3 (no git tag), bar v2.0.0 (latest release: v1.9.0)"
+//
+// 参数:
+//   - [list]   不一致的 package 信息列表
+//   - [locale] 语言包
+//
+// 返回值:
+//   - 格式化后的内容（无不一致时为 "-"）
+func formatReleaseStats(list []PackageInfo, locale Locale) string {
+	if len(list) == 0 {
+		return "-"
+	}
+	result := make([]string, len(list))
+	for i, value := range list {
+		result[i] = value.Name + " v" + value.Version + " (" + strings.Join(releaseMismatches(value, locale), ", ") + ")"
+	}
+	return strings.Join(result, ", ")
+}
+
+// 格式化贡献者排行
+//
+// 参数:
+//   - [ranks]   贡献者排行
+//   - [options] 展示选项
+//   - [locale]  语言包
+//
+// 返回值:
+//   - table：排名表格（前后换行，独占多行）
+//   - avatars：头像墙（可在行内使用）
+//   - 无贡献者时为 "-"
+func formatLeaderboard(ranks []ContributorRank, options LeaderboardOptions, locale Locale) string {
+	if len(ranks) == 0 {
+		return "-"
+	}
+	count := options.Count
+	if count <= 0 {
+		count = defaultLeaderboardCount
+	}
+	ranks = ranks[:min(count, len(ranks))]
+
+	if options.Layout == leaderboardAvatars {
+		avatars := make([]string, len(ranks))
+		for i, rank := range ranks {
+			title := "@" + rank.Login + ": " + fmt.Sprintf(locale.PackagesCount, rank.Packages)
+			avatars[i] = `<a href="` + rank.HtmlUrl + `"><img width="48px" src="` + getGithubAvatarUrl(rank.Id) + `" title="` + html.EscapeString(title) + `" /></a>`
+		}
+		return strings.Join(avatars, " ")
+	}
+
+	markdown := " \n" +
+		"| # | " + locale.Contributor + " | " + locale.Packages + " | " + locale.Contributions + " | \n" +
+		"|:-:|-------------|:-:|:-:| \n"
+	for i, rank := range ranks {
+		markdown += "| " + strconv.Itoa(i+1) +
+			` | <img width="24px" src="` + getGithubAvatarUrl(rank.Id) + `" /> [@` + rank.Login + "](" + rank.HtmlUrl + ")" +
+			" | " + strconv.Itoa(rank.Packages) +
+			" | " + strconv.Itoa(rank.Contributions) +
+			" | \n"
+	}
+	return markdown
+}
+
+// 格式化需要关注的 package（健康度最低的 package 及原因）
+//
+// 参数:
+//   - [list]    存在健康问题的 package（按健康度升序），展示健康度低于阈值的 package
+//   - [options] 展示选项
+//   - [locale]  语言包
+//
+// 返回值:
+//   - 列表（前后换行，独占多行），例如："- 🔴 42 [foo](...): archived repo, 35 open issues"
+//   - 无需要关注的 package 时为 "-"
+func formatAttention(list []PackageInfo, options AttentionOptions, locale Locale) string {
+	count, threshold := options.Count, defaultAttentionThreshold
+	if count <= 0 {
+		count = defaultAttentionCount
+	}
+	if options.Threshold != nil {
+		threshold = *options.Threshold
+	}
+	markdown := ""
+	for _, value := range list {
+		if count == 0 {
+			break
+		}
+		if value.Health.Score >= threshold {
+			continue
+		}
+		markdown += "- " + formatHealthScore(value.Health.Score) + " [" + value.Name + "](" + pubPackageURL(value) + "): " +
+			strings.Join(formatHealthReasons(value.Health.Reasons, locale), ", ") + " \n"
+		count--
+	}
+	if markdown == "" {
+		return "-"
+	}
+	return " \n" + markdown
+}
+
+// 计算 package 汇总统计
+//
+// 参数:
+//   - [packageInfoList] package 信息列表
+//
+// 返回值:
+//   - [PackageStats] 汇总统计（不存在的 package 不计入）
+func computePackageStats(packageInfoList []PackageInfo) PackageStats {
+	stats := PackageStats{Platforms: map[string]int{}}
+	repos := map[string]bool{}
+	contributors := map[int]bool{}
+	ranks := map[int]*ContributorRank{}
+	points, scored := 0.0, 0
+	for _, value := range packageInfoList {
+		if value.Code != 1 {
+			continue
+		}
+		stats.Downloads += value.ScoreInfo.DownloadCount30Days
+		stats.Likes += int(value.ScoreInfo.LikeCount)
+		if len(releaseMismatches(value, locales[defaultLocale])) > 0 {
+			stats.Releases = append(stats.Releases, value)
+		}
+		// 健康度低于 100 时均有扣分原因（见 [healthLowScore]），按阈值筛选见 [formatAttention]
+		if len(value.Health.Reasons) > 0 {
+			stats.Attention = append(stats.Attention, value)
+		}
+		if value.ScoreInfo.MaxPoints > 0 {
+			points += value.ScoreInfo.GrantedPoints
+			scored++
+		}
+		for _, platform := range value.ScoreInfo.TagsPlatform {
+			stats.Platforms[platform]++
+		}
+		if value.GithubUser == "" || value.GithubRepo == "" {
+			continue
+		}
+		// monorepo 中的 package 共享同一仓库，只计一次（贡献者排行按 package 计数）
+		key := strings.ToLower(value.GithubUser + "/" + value.GithubRepo)
+		newRepo := !repos[key]
+		repos[key] = true
+		for _, contributor := range value.GithubContributorsInfo {
+			rank, ok := ranks[contributor.Id]
+			if !ok {
+				rank = &ContributorRank{GithubContributorsInfo: contributor}
+				ranks[contributor.Id] = rank
+			}
+			rank.Packages++
+			if newRepo {
+				rank.Contributions += contributor.Contributions
+			}
+		}
+		if !newRepo {
+			continue
+		}
+		stats.Stars += int(value.GithubBaseInfo.StargazersCount)
+		for _, contributor := range value.GithubContributorsInfo {
+			contributors[contributor.Id] = true
+		}
+	}
+	if scored > 0 {
+		stats.Points = int(math.Round(points / float64(scored)))
+	}
+	stats.Contributors = len(contributors)
+	for _, rank := range ranks {
+		stats.Leaderboard = append(stats.Leaderboard, *rank)
+	}
+	// 按参与的 package 数量、提交数降序，用户名升序（保证输出稳定）
+	sort.Slice(stats.Leaderboard, func(i, j int) bool {
+		a, b := stats.Leaderboard[i], stats.Leaderboard[j]
+		if a.Packages != b.Packages {
+			return a.Packages > b.Packages
+		}
+		if a.Contributions != b.Contributions {
+			return a.Contributions > b.Contributions
+		}
+		return a.Login < b.Login
+	})
+	// 按健康度升序，名称升序（保证输出稳定）
+	sort.SliceStable(stats.Attention, func(i, j int) bool {
+		a, b := stats.Attention[i], stats.Attention[j]
+		if a.Health.Score != b.Health.Score {
+			return a.Health.Score < b.Health.Score
+		}
+		return a.Name < b.Name
+	})
+	return stats
+}
+
+// 格式化每个平台的 package 数量，按数量降序，例如："android 12, ios 10, web 8"
+func formatPlatformStats(platforms map[string]int) string {
+	names := slices.Collect(maps.Keys(platforms))
+	sort.Slice(names, func(i, j int) bool {
+		if platforms[names[i]] != platforms[names[j]] {
+			return platforms[names[i]] > platforms[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = name + " " + strconv.Itoa(platforms[name])
+	}
+	return strings.Join(result, ", ")
+}
+
+// 去除表格区块中的更新时间页脚（最后一行），用于对比内容是否变化
+func stripUpdatedTime(block []byte) []byte {
+	block = bytes.TrimRight(block, "\r\n")
+	if i := bytes.LastIndexByte(block, '\n'); i >= 0 {
+		return block[:i]
+	}
+	return block
+}
+
+// 匹配疑似区块标记（`<!-- md:` 开头），再逐个校验格式
+var markerRegexp = regexp.MustCompile(`<!--\s*md:(.*?)-->`)
+
+// 扫描 Markdown 文件中的区块标记
+//
+// 忽略代码块（``` / ~~~）与行内代码中的标记（例如文档示例）
+//
+// 参数:
+//   - [md] 文件内容
+//
+// 返回值:
+//   - 成对的区块（按出现顺序）
+//   - 标记诊断信息（缺失 begin / end、嵌套、重复、格式错误、未知名称）
+func scanMarkdownMarkers(md []byte) ([]markdownBlock, []MarkerDiagnostic) {
+	blocks := []markdownBlock{}
+	diagnostics := []MarkerDiagnostic{}
+	var open *markdownBlock
+	fence := "" // 当前代码块的围栏，为空时不在代码块中
+	offset := 0
+	for i, line := range splitLines(string(md)) {
+		lineNumber, lineStart := i+1, offset
+		offset += len(line)
+
+		if marker, rest := codeFence(line); marker != "" {
+			if fence == "" {
+				fence = marker
+				continue
+			}
+			if marker[0] == fence[0] && len(marker) >= len(fence) && strings.TrimSpace(rest) == "" {
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			continue
+		}
+
+		for _, loc := range markerRegexp.FindAllStringSubmatchIndex(line, -1) {
+			if strings.Count(line[:loc[0]], "`")%2 == 1 {
+				continue // 行内代码
+			}
+			marker := line[loc[0]:loc[1]]
+			fields := strings.Fields(line[loc[2]:loc[3]])
+			if len(fields) != 2 || (fields[1] != "begin" && fields[1] != "end") {
+				diagnostics = append(diagnostics, MarkerDiagnostic{Line: lineNumber, Message: fmt.Sprintf("malformed marker %q, want <!-- md:NAME begin --> or <!-- md:NAME end -->", marker), Warning: true})
+				continue
+			}
+			name, kind := fields[0], fields[1]
+			if !slices.Contains(markerNames, name) {
+				diagnostics = append(diagnostics, MarkerDiagnostic{Line: lineNumber, Message: fmt.Sprintf("unknown marker %q", marker), Warning: true})
+				continue
+			}
+			switch {
+			case kind == "begin" && open != nil:
+				diagnostics = append(diagnostics, MarkerDiagnostic{Line: lineNumber, Message: fmt.Sprintf("nested marker %q inside %q (line %d)", marker, open.Name, open.Line)})
+			case kind == "begin":
+				open = &markdownBlock{Name: name, Line: lineNumber, Start: lineStart + loc[1]}
+			case open == nil:
+				diagnostics = append(diagnostics, MarkerDiagnostic{Line: lineNumber, Message: fmt.Sprintf("%q without begin marker", marker)})
+			case open.Name != name:
+				diagnostics = append(diagnostics, MarkerDiagnostic{Line: lineNumber, Message: fmt.Sprintf("%q does not match begin marker %q (line %d)", marker, open.Name, open.Line)})
+			default:
+				open.End = lineStart + loc[0]
+				blocks = append(blocks, *open)
+				open = nil
+			}
+		}
+	}
+	if open != nil {
+		diagnostics = append(diagnostics, MarkerDiagnostic{Line: open.Line, Message: fmt.Sprintf("%q begin marker without end", open.Name)})
+	}
+
+	// 表格区块应唯一（其余区块可在正文中多次引用）
+	tableLine := 0
+	for _, block := range blocks {
+		if block.Name != markerTable {
+			continue
+		}
+		if tableLine != 0 {
+			diagnostics = append(diagnostics, MarkerDiagnostic{Line: block.Line, Message: fmt.Sprintf("duplicate marker %q (first at line %d)", block.Name, tableLine), Warning: true})
+			continue
+		}
+		tableLine = block.Line
+	}
+	return blocks, diagnostics
+}
+
+// 解析代码块围栏（``` 或 ~~~，最多缩进 3 个空格）
+//
+// 返回值:
+//   - 围栏（非围栏行时为空）
+//   - 围栏之后的内容
+func codeFence(line string) (string, string) {
+	line = strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", ""
+	}
+	for _, c := range []string{"`", "~"} {
+		if n := len(trimmed) - len(strings.TrimLeft(trimmed, c)); n >= 3 {
+			return trimmed[:n], trimmed[n:]
+		}
+	}
+	return "", ""
+}
+
+// 解析输出目标列表
+//
+// 每行一个目标："文件 key=value ..."，值包含空格时使用双引号；空行与 `#` 开头的行忽略。
+// 可选 key：sortField、sortMode、groupByRepo、issueLabel、activity、contributorsLayout、contributorsCount、contributorsSize、leaderboardLayout、leaderboardCount、health、attentionCount、attentionThreshold、locale、render、markers（`,` 逗号分割的区块名称，可省略 `PubDashboard-` 前缀）、filter（见 [parseFilter]）
+//
+// 参数:
+//   - [value]    输出目标列表，例如："README.md\nREADME_CN.md sortField=pubDownloads issueLabel=\"p: {name}\""
+//   - [defaults] 未指定时的默认选项（来自命令行参数）
+//   - [dir]      相对路径的基准目录
+//
+// 返回值:
+//   - 输出目标列表
+func parseTargets(value string, defaults Target, dir string) ([]Target, error) {
+	targets := []Target{}
+	for i, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitTargetFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target (line %d): %w", i+1, err)
+		}
+		target := defaults
+		target.Filename = fields[0]
+		for _, field := range fields[1:] {
+			key, val, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid target (line %d): %q, want key=value", i+1, field)
+			}
+			switch key {
+			case "sortField":
+				target.Table.SortField = val
+			case "sortMode":
+				target.SortMode = val
+			case "groupByRepo":
+				groupByRepo, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid target (line %d): groupByRepo %q", i+1, val)
+				}
+				target.Table.GroupByRepo = groupByRepo
+			case "issueLabel":
+				target.Table.IssueLabel = val
+			case "activity":
+				activity, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid target (line %d): activity %q", i+1, val)
+				}
+				target.Table.Activity = activity
+			case "contributorsLayout":
+				if !slices.Contains(contributorsLayouts, val) {
+					return nil, fmt.Errorf("invalid target (line %d): unknown contributors layout %q", i+1, val)
+				}
+				target.Table.ContributorsLayout = val
+			case "contributorsCount", "contributorsSize":
+				number, err := strconv.Atoi(val)
+				if err != nil || number <= 0 {
+					return nil, fmt.Errorf("invalid target (line %d): %s %q", i+1, key, val)
+				}
+				if key == "contributorsCount" {
+					target.Table.ContributorsCount = number
+				} else {
+					target.Table.ContributorsSize = number
+				}
+			case "leaderboardLayout":
+				if !slices.Contains(leaderboardLayouts, val) {
+					return nil, fmt.Errorf("invalid target (line %d): unknown leaderboard layout %q", i+1, val)
+				}
+				target.Leaderboard.Layout = val
+			case "leaderboardCount":
+				number, err := strconv.Atoi(val)
+				if err != nil || number <= 0 {
+					return nil, fmt.Errorf("invalid target (line %d): leaderboardCount %q", i+1, val)
+				}
+				target.Leaderboard.Count = number
+			case "health":
+				health, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid target (line %d): health %q", i+1, val)
+				}
+				target.Table.Health = health
+			case "attentionCount":
+				number, err := strconv.Atoi(val)
+				if err != nil || number <= 0 {
+					return nil, fmt.Errorf("invalid target (line %d): attentionCount %q", i+1, val)
+				}
+				target.Attention.Count = number
+			case "attentionThreshold":
+				// 0 表示不展示任何 package
+				number, err := strconv.Atoi(val)
+				if err != nil || number < 0 {
+					return nil, fmt.Errorf("invalid target (line %d): attentionThreshold %q", i+1, val)
+				}
+				target.Attention.Threshold = &number
+			case "locale":
+				if _, ok := locales[val]; !ok {
+					return nil, fmt.Errorf("invalid target (line %d): unknown locale %q", i+1, val)
+				}
+				target.Table.Locale = val
+			case "render":
+				if !slices.Contains(renderModes, val) {
+					return nil, fmt.Errorf("invalid target (line %d): unknown render %q", i+1, val)
+				}
+				target.Table.Render = val
+			case "markers":
+				target.Markers = nil
+				for _, name := range removeDuplicates(strings.Split(val, ",")) {
+					if name != markerTable && !strings.HasPrefix(name, markerTable+"-") {
+						name = markerTable + "-" + name
+					}
+					if !slices.Contains(markerNames, name) {
+						return nil, fmt.Errorf("invalid target (line %d): unknown marker %q", i+1, name)
+					}
+					target.Markers = append(target.Markers, name)
+				}
+			case "filter":
+				filter, err := parseFilter(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid target (line %d): %w", i+1, err)
+				}
+				target.Filter = filter
+			default:
+				return nil, fmt.Errorf("invalid target (line %d): unknown key %q", i+1, key)
+			}
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		targets = append(targets, defaults)
+	}
+	for i := range targets {
+		targets[i].Filename = resolvePath(dir, targets[i].Filename)
+	}
+	return targets, nil
+}
+
+// 根据输出目标推导需要获取的 Github 数据，跳过没有区块展示、也没有排序 / 过滤 / 检查使用的请求
+//
+// 参数:
+//   - [targets]        输出目标
+//   - [weights]        健康度权重
+//   - [strictVersions] 是否检查 pub 版本对应的 git tag
+//
+// 返回值:
+//   - [FetchOptions] 抓取选项（仅设置 Activity、OpenCounts、Releases、IssueLabels）
+func targetFetchOptions(targets []Target, weights map[string]int, strictVersions bool) FetchOptions {
+	options := FetchOptions{Releases: strictVersions}
+	for _, target := range targets {
+		markers := targetMarkers(target)
+		table := slices.Contains(markers, markerTable)
+		fields := []string{target.Table.SortField}
+		for _, condition := range target.Filter {
+			fields = append(fields, condition.Field)
+		}
+		health := (table && target.Table.Health) || slices.Contains(markers, markerAttention) || slices.Contains(fields, "health")
+
+		// badge 渲染由 Shields 展示数量，native / svg 渲染使用获取的数量
+		counted := table && (target.Table.Render == renderNative || target.Table.Render == renderSVG)
+		if counted && target.Table.IssueLabel != "" && !slices.Contains(options.IssueLabels, target.Table.IssueLabel) {
+			options.IssueLabels = append(options.IssueLabels, target.Table.IssueLabel)
+		}
+		if counted || slices.Contains(fields, "githubIssues") || slices.Contains(fields, "githubPullRequests") ||
+			(health && (weights[healthIssues] > 0 || weights[healthPullRequests] > 0)) {
+			options.OpenCounts = true
+		}
+		// 表格行与 releases 区块展示 pub 版本与 tag / release 不一致的原因
+		if table || slices.Contains(markers, markerReleases) {
+			options.Releases = true
+		}
+		if table && target.Table.Activity {
+			options.Activity = true
+		}
+	}
+	return options
+}
+
+// 输出目标会更新的区块名称
+//
+// 返回值:
+//   - 指定 markers 时为指定的区块，否则为文件中已有的区块（文件无法读取时为全部区块）
+func targetMarkers(target Target) []string {
+	if len(target.Markers) > 0 {
+		return target.Markers
+	}
+	md, err := os.ReadFile(target.Filename)
+	if err != nil {
+		return markerNames
+	}
+	blocks, _ := scanMarkdownMarkers(md)
+	names := []string{}
+	for _, block := range blocks {
+		names = append(names, block.Name)
+	}
+	return names
+}
+
+// 按空白分割输出目标的字段（支持双引号包裹的值，如：issueLabel="p: {name}"）
+func splitTargetFields(line string) ([]string, error) {
+	fields := []string{}
+	field := strings.Builder{}
+	inField, quoted := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// SVG 徽章
+type Badge struct {
+	Label   string // 左侧文字（有图标时为空）
+	Icon    string // 左侧图标（data URI）
+	Message string // 右侧文字
+	Color   string // 右侧背景色
+}
+
+// 徽章类型
+var badgeKinds = []string{"downloads", "likes", "points", "stars", "issues", "pulls"}
+
+// 徽章目录中记录本工具生成文件的清单（JSON 数组），仅删除清单中的徽章，避免误删用户自己的文件
+const badgeManifestFile = ".pub-dashboard-badges.json"
+
+// 获取徽章文件名，非默认语言时带语言后缀，例如："foo-downloads.svg" "foo-downloads.zh-CN.svg"
+func badgeFilename(name string, kind string, locale string) string {
+	if locale == "" || locale == defaultLocale {
+		return name + "-" + kind + ".svg"
+	}
+	return name + "-" + kind + "." + locale + ".svg"
+}
+
+// 生成 package 的 SVG 徽章（与表格中的引用一致）
+//
+// 参数:
+//   - [packageInfoList] package 信息列表
+//   - [options]         表格选项（语言、issueLabel）
+//
+// 返回值:
+//   - 徽章文件名 -> SVG 内容
+func collectBadges(packageInfoList []PackageInfo, options TableOptions) map[string][]byte {
+	localeName := options.Locale
+	locale := options.locale()
+	badges := map[string][]byte{}
+	for _, value := range packageInfoList {
+		if value.Code != 1 {
+			continue
+		}
+		if value.ScoreInfo.MaxPoints > 0 {
+			badges[badgeFilename(value.Name, "downloads", localeName)] = renderBadgeSVG(Badge{
+				Icon: downloadIcon, Message: locale.formatCount(value.ScoreInfo.DownloadCount30Days) + "/" + locale.PerMonth, Color: "#4AC51C",
+			})
+			badges[badgeFilename(value.Name, "likes", localeName)] = renderBadgeSVG(Badge{
+				Label: "likes", Message: locale.formatCount(int(value.ScoreInfo.LikeCount)), Color: "#168AFD",
+			})
+			badges[badgeFilename(value.Name, "points", localeName)] = renderBadgeSVG(Badge{
+				Icon: pointIcon, Message: strconv.Itoa(int(value.ScoreInfo.GrantedPoints)) + "/" + strconv.Itoa(int(value.ScoreInfo.MaxPoints)), Color: pointsColor(value.ScoreInfo),
+			})
+		}
+		if value.GithubUser != "" && value.GithubRepo != "" {
+			badges[badgeFilename(value.Name, "stars", localeName)] = renderBadgeSVG(Badge{
+				Label: "stars", Message: locale.formatCount(int(value.GithubBaseInfo.StargazersCount)), Color: "#1F2328",
+			})
+			badges[badgeFilename(value.Name, "issues", localeName)] = renderBadgeSVG(Badge{
+				Label: "issues", Message: locale.formatCount(value.GithubOpenIssues), Color: "#DFB317",
+			})
+			badges[badgeFilename(value.Name, "pulls", localeName)] = renderBadgeSVG(Badge{
+				Label: "pull requests", Message: locale.formatCount(value.GithubOpenPullRequests), Color: "#007EC6",
+			})
+		}
+		if isIssueLabelScoped(value, options) {
+			// 按 package label 过滤的数量
+			counts, ok := value.GithubLabelCounts[strings.ReplaceAll(options.IssueLabel, "{name}", value.Name)]
+			if !ok {
+				continue
+			}
+			badges[badgeFilename(value.Name, "issues-label", localeName)] = renderBadgeSVG(Badge{
+				Label: "issues", Message: locale.formatCount(counts.Issues), Color: "#DFB317",
+			})
+			badges[badgeFilename(value.Name, "pulls-label", localeName)] = renderBadgeSVG(Badge{
+				Label: "pull requests", Message: locale.formatCount(counts.PullRequests), Color: "#007EC6",
+			})
+		}
+	}
+	return badges
+}
+
+// 按 pub points 得分比例获取徽章颜色
+func pointsColor(score PackageScoreInfo) string {
+	ratio := score.GrantedPoints / score.MaxPoints
+	switch {
+	case ratio >= 0.9:
+		return "#4AC51C"
+	case ratio >= 0.7:
+		return "#DFB317"
+	}
+	return "#E05D44"
+}
+
+// 估算文字宽度（Verdana 11px，取整像素，保证输出稳定）
+func badgeTextWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("il.,:;|!'", r):
+			width += 3
+		case r == ' ' || r == '/' || r == 'f' || r == 't' || r == 'r':
+			width += 4
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			width += 9
+		case r >= '0' && r <= '9', r >= 'A' && r <= 'Z':
+			width += 7
+		case r > 0x2E80:
+			width += 11 // CJK
+		default:
+			width += 6
+		}
+	}
+	return width
+}
+
+// 生成 SVG 徽章（Shields flat 风格，不含随机 id 与时间，相同输入输出一致）
+func renderBadgeSVG(badge Badge) []byte {
+	const height, padding, iconSize = 20, 6, 14
+	leftWidth := padding * 2
+	if badge.Icon != "" {
+		leftWidth += iconSize
+	}
+	if badge.Label != "" {
+		leftWidth += badgeTextWidth(badge.Label)
+		if badge.Icon != "" {
+			leftWidth += 3
+		}
+	}
+	rightWidth := badgeTextWidth(badge.Message) + padding*2
+	width := leftWidth + rightWidth
+	title := strings.TrimPrefix(badge.Label+": "+badge.Message, ": ")
+
+	svg := bytes.NewBuffer(nil)
+	fmt.Fprintf(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s">`, width, height, html.EscapeString(title))
+	fmt.Fprintf(svg, `<title>%s</title>`, html.EscapeString(title))
+	svg.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(svg, `<clipPath id="r"><rect width="%d" height="%d" rx="3" fill="#fff"/></clipPath>`, width, height)
+	fmt.Fprintf(svg, `<g clip-path="url(#r)"><rect width="%d" height="%d" fill="#555"/><rect x="%d" width="%d" height="%d" fill="%s"/><rect width="%d" height="%d" fill="url(#s)"/></g>`,
+		leftWidth, height, leftWidth, rightWidth, height, badge.Color, width, height)
+	svg.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	x := padding
+	if badge.Icon != "" {
+		fmt.Fprintf(svg, `<image x="%d" y="3" width="%d" height="%d" href="%s"/>`, x, iconSize, iconSize, badge.Icon)
+		x += iconSize + 3
+	}
+	if badge.Label != "" {
+		fmt.Fprintf(svg, `<text x="%d" y="14">%s</text>`, x+badgeTextWidth(badge.Label)/2, html.EscapeString(badge.Label))
+	}
+	fmt.Fprintf(svg, `<text x="%d" y="14">%s</text>`, leftWidth+rightWidth/2, html.EscapeString(badge.Message))
+	svg.WriteString("</g></svg>\n")
+	return svg.Bytes()
+}
+
+// 更新徽章目录（仅写入内容变化的徽章，并删除上一次生成、本次不再引用的徽章）
+//
+// 生成的徽章记录在 [badgeManifestFile] 中，清单以外的文件不会被删除。
+//
+// 参数:
+//   - [dir]    徽章目录
+//   - [badges] 徽章文件名 -> SVG 内容
+//   - [mode]   更新模式
+//
+// 返回值:
+//   - 徽章目录是否有变化
+func updateBadges(dir string, badges map[string][]byte, mode UpdateMode) (bool, error) {
+	printErrTitle := "🏷️❌ updateBadges: "
+	manifest, err := json.MarshalIndent(slices.Sorted(maps.Keys(badges)), "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	files := maps.Clone(badges)
+	files[badgeManifestFile] = append(manifest, '\n')
+	changes := []string{}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		old, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("%s%w", printErrTitle, err)
+		}
+		if err == nil && bytes.Equal(old, files[name]) {
+			continue
+		}
+		changes = append(changes, name)
+	}
+	// 上一次生成的徽章
+	previous := []string{}
+	if data, err := os.ReadFile(filepath.Join(dir, badgeManifestFile)); err == nil {
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return false, fmt.Errorf("%s%s: %w", printErrTitle, badgeManifestFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	removed := []string{}
+	for _, name := range previous {
+		// 清单中只应有目录下的文件名
+		if _, ok := files[name]; ok || name != filepath.Base(name) || name == "." || name == ".." {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			removed = append(removed, name)
+		}
+	}
+	if len(changes) == 0 && len(removed) == 0 {
+		fmt.Println("🏷️✅ updateBadges: No changes")
+		return false, nil
+	}
+	if mode != UpdateModeWrite {
+		for _, name := range changes {
+			fmt.Printf("🏷️ %s: %s\n", mode, filepath.Join(dir, name))
+		}
+		for _, name := range removed {
+			fmt.Printf("🏷️ %s: remove %s\n", mode, filepath.Join(dir, name))
+		}
+		return true, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("%s%w", printErrTitle, err)
+	}
+	for _, name := range changes {
+		if err := writeFileAtomic(filepath.Join(dir, name), files[name], 0644); err != nil {
+			return false, fmt.Errorf("%s%w", printErrTitle, err)
+		}
+	}
+	for _, name := range removed {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return false, fmt.Errorf("%s%w", printErrTitle, err)
+		}
+	}
+	fmt.Printf("🏷️✅ updateBadges: %d updated, %d removed\n", len(changes), len(removed))
+	return true, nil
+}
+
+// 输出文件是否变化（GitHub Actions 的 step output：changed=true|false）
+//
+// 未在 GitHub Actions 中运行（无 GITHUB_OUTPUT）时仅打印
+//
+// 参数:
+//   - [changed] 文件内容是否有变化
+func writeChangedOutput(changed bool) error {
+	fmt.Printf("📄 changed: %t\n", changed)
+	outputFile := os.Getenv("GITHUB_OUTPUT")
+	if outputFile == "" {
+		return nil
+	}
+	f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("📄❌ GITHUB_OUTPUT: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "changed=%t\n", changed); err != nil {
+		return fmt.Errorf("📄❌ GITHUB_OUTPUT: %w", err)
+	}
+	return nil
+}
+
+// 运行快照：保存每个 package 的关键数据，用于与下一次运行对比
+type Snapshot struct {
+	Packages map[string]PackageSnapshot `json:"packages"` // 按 package 名称
+}
+
+// package 快照
+type PackageSnapshot struct {
+	Version    string   `json:"version"`
+	Points     int      `json:"points"`
+	MaxPoints  int      `json:"maxPoints"`
+	Downloads  int      `json:"downloads"` // 30 天下载量
+	Likes      int      `json:"likes"`
+	Stars      int      `json:"stars"`
+	Archived   bool     `json:"archived"`
+	Advisories []string `json:"advisories,omitempty"`
+}
+
+// 运行间的回退告警
+type Alert struct {
+	Package  string `json:"package"`
+	Kind     string `json:"kind"`               // pointsDrop | advisory | archived | downloadsDrop
+	Previous string `json:"previous,omitempty"` // 上一次运行的值
+	Current  string `json:"current,omitempty"`  // 本次运行的值（新安全公告的 ID）
+	Message  string `json:"message"`
+}
+
+// 告警类型
+const (
+	alertPoints    = "pointsDrop"    // pub points 下降
+	alertAdvisory  = "advisory"      // 新的安全公告
+	alertArchived  = "archived"      // 仓库被归档
+	alertDownloads = "downloadsDrop" // 30 天下载量下降超过阈值
+)
+
+// 默认的下载量下降告警阈值（百分比）
+const defaultAlertDownloadsDrop = 50
+
+// 告警输出选项
+type AlertOptions struct {
+	File    string // 告警 JSON 文件，为空时不写入
+	Webhook string // 告警 POST 地址，为空时不发送
+}
+
+// 生成运行快照
+//
+// 参数:
+//   - [packageInfoList] package 信息列表
+//
+// 返回值:
+//   - [Snapshot] 快照（无法获取信息的 package 不计入）
+func newSnapshot(packageInfoList []PackageInfo) Snapshot {
+	snapshot := Snapshot{Packages: map[string]PackageSnapshot{}}
+	for _, value := range packageInfoList {
+		if value.Code != 1 {
+			continue
+		}
+		snapshot.Packages[value.Name] = PackageSnapshot{
+			Version:    value.Version,
+			Points:     int(value.ScoreInfo.GrantedPoints),
+			MaxPoints:  int(value.ScoreInfo.MaxPoints),
+			Downloads:  value.ScoreInfo.DownloadCount30Days,
+			Likes:      int(value.ScoreInfo.LikeCount),
+			Stars:      int(value.GithubBaseInfo.StargazersCount),
+			Archived:   value.GithubBaseInfo.Archived,
+			Advisories: value.Advisories,
+		}
+	}
+	return snapshot
+}
+
+// 读取上一次运行的快照
+//
+// 返回值:
+//   - [Snapshot] 快照（文件不存在时为 nil，如首次运行）
+func loadSnapshot(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("📸❌ snapshot: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("📸❌ snapshot: %s: %w", filename, err)
+	}
+	return &snapshot, nil
+}
+
+// 写入运行快照（仅 write 模式，内容无变化时不写入）
+//
+// 参数:
+//   - [filename] 快照文件
+//   - [snapshot] 快照
+//   - [mode]     更新模式
+//
+// 返回值:
+//   - 文件内容是否有变化
+func writeSnapshot(filename string, snapshot Snapshot, mode UpdateMode) (bool, error) {
+	if mode != UpdateModeWrite {
+		return false, nil
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("📸❌ snapshot: %w", err)
+	}
+	data = append(data, '\n')
+	if old, err := os.ReadFile(filename); err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return false, fmt.Errorf("📸❌ snapshot: %w", err)
+	}
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return false, fmt.Errorf("📸❌ snapshot: %w", err)
+	}
+	fmt.Printf("📸✅ snapshot: %s\n", filename)
+	return true, nil
+}
+
+// 对比两次运行的快照，生成回退告警
+//
+// 仅对比两次都存在的 package：pub points 下降、新的安全公告、仓库被归档、30 天下载量下降超过阈值
+//
+// 参数:
+//   - [previous]      上一次运行的快照
+//   - [current]       本次运行的快照
+//   - [downloadsDrop] 下载量下降告警阈值（百分比，<= 0 时不告警）
+//
+// 返回值:
+//   - 告警列表（按 package 名称排序）
+func compareSnapshots(previous Snapshot, current Snapshot, downloadsDrop float64) []Alert {
+	alerts := []Alert{}
+	names := slices.Sorted(maps.Keys(current.Packages))
+	for _, name := range names {
+		cur := current.Packages[name]
+		prev, ok := previous.Packages[name]
+		if !ok {
+			continue
+		}
+		if prev.MaxPoints > 0 && cur.MaxPoints > 0 && cur.Points < prev.Points {
+			alerts = append(alerts, Alert{Package: name, Kind: alertPoints, Previous: strconv.Itoa(prev.Points), Current: strconv.Itoa(cur.Points),
+				Message: fmt.Sprintf("pub points dropped from %d to %d", prev.Points, cur.Points)})
+		}
+		for _, advisory := range cur.Advisories {
+			if !slices.Contains(prev.Advisories, advisory) {
+				alerts = append(alerts, Alert{Package: name, Kind: alertAdvisory, Current: advisory,
+					Message: "new security advisory " + advisory})
+			}
+		}
+		if cur.Archived && !prev.Archived {
+			alerts = append(alerts, Alert{Package: name, Kind: alertArchived, Message: "Github repo was archived"})
+		}
+		if downloadsDrop > 0 && prev.Downloads > 0 {
+			drop := float64(prev.Downloads-cur.Downloads) / float64(prev.Downloads) * 100
+			if drop > downloadsDrop {
+				alerts = append(alerts, Alert{Package: name, Kind: alertDownloads, Previous: strconv.Itoa(prev.Downloads), Current: strconv.Itoa(cur.Downloads),
+					Message: fmt.Sprintf("30-day downloads fell %.0f%% from %d to %d", drop, prev.Downloads, cur.Downloads)})
+			}
+		}
+	}
+	return alerts
+}
+
+// 输出告警：打印、写入 JSON 文件、GitHub Actions job summary（GITHUB_STEP_SUMMARY）、POST 到 webhook
+//
+// dry-run / check 模式下快照不会更新，仅打印告警，避免每次运行重复发送相同的告警。
+//
+// 参数:
+//   - [ctx]     上下文
+//   - [client]  共享 HTTP Client
+//   - [alerts]  告警列表
+//   - [options] 输出选项
+//   - [mode]    更新模式
+//
+// 返回值:
+//   - 错误（某个输出失败时继续其余输出，合并返回）
+func reportAlerts(ctx context.Context, client *HTTPClient, alerts []Alert, options AlertOptions, mode UpdateMode) error {
+	for _, alert := range alerts {
+		fmt.Printf("🚨 %s: %s\n", alert.Package, alert.Message)
+	}
+	if mode != UpdateModeWrite {
+		return nil
+	}
+	var errs []error
+	if options.File != "" {
+		// 无告警时同样写入（空列表），便于后续步骤读取
+		data, _ := json.MarshalIndent(alerts, "", "  ")
+		if err := writeFileAtomic(options.File, append(data, '\n'), 0644); err != nil {
+			errs = append(errs, fmt.Errorf("🚨❌ alerts file: %w", err))
+		}
+	}
+	if len(alerts) == 0 {
+		return errors.Join(errs...)
+	}
+	if summaryFile := os.Getenv("GITHUB_STEP_SUMMARY"); summaryFile != "" {
+		if err := appendFile(summaryFile, formatAlertsSummary(alerts)); err != nil {
+			errs = append(errs, fmt.Errorf("🚨❌ GITHUB_STEP_SUMMARY: %w", err))
+		}
+	}
+	if options.Webhook != "" {
+		payload, _ := json.Marshal(map[string][]Alert{"alerts": alerts})
+		_, status, err := httpPostWithRetry(ctx, client, options.Webhook, map[string]string{"Content-Type": "application/json"}, payload)
+		if err == nil && (status < 200 || status > 299) {
+			err = fmt.Errorf("unexpected status %d", status)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("🚨❌ alerts webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 格式化 job summary 中的告警表格
+func formatAlertsSummary(alerts []Alert) string {
+	summary := "### 🚨 pub-dashboard alerts\n\n" +
+		"| Package | Alert |\n" +
+		"|---------|-------|\n"
+	for _, alert := range alerts {
+		summary += "| " + alert.Package + " | " + alert.Message + " |\n"
+	}
+	return summary + "\n"
+}
+
+// 追加写入文件（不存在时创建）
+func appendFile(filename string, content string) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+// 运行结果通知目标
+type NotifySink struct {
+	Kind string // slack | discord | teams | custom
+	URL  string // webhook 地址
+}
+
+// 通知格式
+const (
+	notifySlack   = "slack"   // {"text": "..."}
+	notifyDiscord = "discord" // {"content": "..."}
+	notifyTeams   = "teams"   // MessageCard
+	notifyCustom  = "custom"  // 自定义模板（见 [NotifyOptions.Template]）
+)
+
+// 支持的通知格式
+var notifyKinds = []string{notifySlack, notifyDiscord, notifyTeams, notifyCustom}
+
+// 发送通知的时机
+const (
+	notifyOnChanges = "changes" // 文件有变化、package 有增减或有失败时
+	notifyOnAlways  = "always"  // 每次运行
+)
+
+// 支持的通知时机
+var notifyOnModes = []string{notifyOnChanges, notifyOnAlways}
+
+// discordMaxContent 是 Discord 消息内容的长度上限（字符）。
+const discordMaxContent = 2000
+
+// 通知选项
+type NotifyOptions struct {
+	Sinks    []NotifySink
+	On       string             // 发送时机 changes（默认）| always
+	Template *template.Template // custom 格式的 JSON 模板，参数为 [RunReport]
+}
+
+// 运行结果（通知内容）
+type RunReport struct {
+	Repository string   `json:"repository,omitempty"` // 运行所在仓库（GITHUB_REPOSITORY）
+	RunURL     string   `json:"runURL,omitempty"`     // GitHub Actions 运行地址
+	Mode       string   `json:"mode"`                 // 更新模式 write | dry-run | check
+	Summary    string   `json:"summary"`              // 变化摘要
+	Changed    bool     `json:"changed"`              // 文件是否有变化
+	Files      []string `json:"files"`                // 有变化的文件（相对于 dir）
+	Packages   int      `json:"packages"`             // 已获取信息的 package 数量
+	Added      []string `json:"added"`                // 上一次运行后新增的 package（需设置 snapshot）
+	Removed    []string `json:"removed"`              // 上一次运行后移除的 package（需设置 snapshot）
+	Failures   []string `json:"failures"`             // 失败信息
+}
+
+// 记录失败信息
+func (report *RunReport) fail(err error) {
+	fmt.Println(err)
+	report.Failures = append(report.Failures, err.Error())
+}
+
+// 记录有变化的文件（相对于 [dir]）
+func (report *RunReport) changedFile(dir string, filename string) {
+	if rel, err := filepath.Rel(dir, filename); err == nil {
+		filename = filepath.ToSlash(rel)
+	}
+	report.Files = append(report.Files, filename)
+}
+
+// 解析通知目标
+//
+// 参数:
+//   - [value] 通知目标（空白或换行分割的 "格式=地址"），例如："slack=https://hooks.slack.com/services/xxx teams=https://xxx"
+//
+// 返回值:
+//   - 通知目标列表（value 为空时为空）
+func parseNotifySinks(value string) ([]NotifySink, error) {
+	sinks := []NotifySink{}
+	for _, item := range strings.Fields(value) {
+		kind, rawURL, ok := strings.Cut(item, "=")
+		// 地址中可能包含令牌，错误信息中不输出
+		if !ok || !slices.Contains(notifyKinds, kind) {
+			return nil, fmt.Errorf("invalid notify sink %q, want kind=url (%s)", kind, strings.Join(notifyKinds, " | "))
+		}
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid notify sink %q: want an http(s) url", kind)
+		}
+		sinks = append(sinks, NotifySink{Kind: kind, URL: rawURL})
+	}
+	return sinks, nil
+}
+
+// 解析 custom 通知的 JSON 模板（text/template），提供 json 函数输出 JSON 值，例如：{"text": {{json .Summary}}}
+func parseNotifyTemplate(filename string) (*template.Template, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("🔔❌ notify template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(filename)).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("🔔❌ notify template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// 对比快照中的 package 列表
+//
+// 返回值:
+//   - 新增的 package（按名称排序）
+//   - 移除的 package（按名称排序）
+func diffSnapshotPackages(previous Snapshot, current Snapshot) ([]string, []string) {
+	added, removed := []string{}, []string{}
+	for _, name := range slices.Sorted(maps.Keys(current.Packages)) {
+		if _, ok := previous.Packages[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(previous.Packages)) {
+		if _, ok := current.Packages[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+// 生成变化摘要，例如："Updated README.md, README_CN.md" "No changes"
+func (report RunReport) summary() string {
+	files := strings.Join(report.Files, ", ")
+	switch {
+	case len(report.Files) == 0:
+		return "No changes"
+	case report.Mode == UpdateModeDryRun.String():
+		return "Would update " + files
+	case report.Mode == UpdateModeCheck.String():
+		return "Stale: " + files
+	}
+	return "Updated " + files
+}
+
+// 格式化通知文本（Slack、Discord、Teams 均支持的 Markdown 子集）
+func (report RunReport) text() string {
+	lines := []string{"*pub-dashboard*: " + report.Summary}
+	if report.Repository != "" {
+		lines[0] = "*pub-dashboard* (" + report.Repository + "): " + report.Summary
+	}
+	lines = append(lines, "Packages: "+strconv.Itoa(report.Packages))
+	if len(report.Added) > 0 {
+		lines = append(lines, "Added: "+strings.Join(report.Added, ", "))
+	}
+	if len(report.Removed) > 0 {
+		lines = append(lines, "Removed: "+strings.Join(report.Removed, ", "))
+	}
+	if len(report.Failures) > 0 {
+		lines = append(lines, "Failures:")
+		for _, failure := range report.Failures {
+			lines = append(lines, "• "+failure)
+		}
+	}
+	if report.RunURL != "" {
+		lines = append(lines, report.RunURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 是否需要发送通知
+func (options NotifyOptions) shouldNotify(report RunReport) bool {
+	if len(options.Sinks) == 0 {
+		return false
+	}
+	return options.On == notifyOnAlways || report.Changed || len(report.Added) > 0 || len(report.Removed) > 0 || len(report.Failures) > 0
+}
+
+// 生成通知请求体
+//
+// 参数:
+//   - [kind]   通知格式
+//   - [report] 运行结果
+//   - [tmpl]   custom 格式的 JSON 模板
+//
+// 返回值:
+//   - JSON 请求体
+func notifyPayload(kind string, report RunReport, tmpl *template.Template) ([]byte, error) {
+	text := report.text()
+	switch kind {
+	case notifySlack:
+		return json.Marshal(map[string]string{"text": text})
+	case notifyDiscord:
+		if runes := []rune(text); len(runes) > discordMaxContent {
+			text = string(runes[:discordMaxContent-1]) + "…"
+		}
+		return json.Marshal(map[string]string{"content": text})
+	case notifyTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  report.Summary,
+			"title":    "pub-dashboard",
+			// MessageCard 的换行需使用 Markdown 段落
+			"text": strings.ReplaceAll(text, "\n", "\n\n"),
+		})
+	case notifyCustom:
+		if tmpl == nil {
+			return nil, errors.New("custom notify sink needs a template")
+		}
+		buf := bytes.Buffer{}
+		if err := tmpl.Execute(&buf, report); err != nil {
+			return nil, err
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, errors.New("notify template did not render valid JSON")
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown notify sink %q", kind)
+}
+
+// 发送运行结果通知（某个目标失败时继续发送其余目标）
+//
+// 仅在写入模式下发送，dry-run / check（如 PR 检查）不通知。
+//
+// 参数:
+//   - [ctx]     上下文
+//   - [client]  共享 HTTP Client（POST 沿用 GET 的退避与限流重试，见 [httpPostWithRetry]）
+//   - [report]  运行结果
+//   - [options] 通知选项
+//   - [mode]    更新模式
+//
+// 返回值:
+//   - 错误（合并所有失败的目标）
+func sendNotifications(ctx context.Context, client *HTTPClient, report RunReport, options NotifyOptions, mode UpdateMode) error {
+	if !options.shouldNotify(report) {
+		return nil
+	}
+	if mode != UpdateModeWrite {
+		fmt.Printf("🔔 %s: skip notify\n", mode)
+		return nil
+	}
+	var errs []error
+	for _, sink := range options.Sinks {
+		payload, err := notifyPayload(sink.Kind, report, options.Template)
+		if err == nil {
+			var status int
+			_, status, err = httpPostWithRetry(ctx, client, sink.URL, map[string]string{"Content-Type": "application/json"}, payload)
+			if err == nil && (status < 200 || status > 299) {
+				err = fmt.Errorf("unexpected status %d", status)
+			}
+		}
+		if err != nil {
+			// 地址中可能包含令牌，仅输出 host
+			host := sink.URL
+			if u, parseErr := url.Parse(sink.URL); parseErr == nil {
+				host = u.Host
+			}
+			errs = append(errs, fmt.Errorf("🔔❌ notify %s (%s): %w", sink.Kind, host, err))
+			continue
+		}
+		fmt.Printf("🔔✅ notify %s: sent\n", sink.Kind)
+	}
+	return errors.Join(errs...)
+}
+
+// 获取 GitHub Actions 运行信息（不在 GitHub Actions 中运行时为空）
+//
+// 返回值:
+//   - 仓库名称，例如："AmosHuKe/pub-dashboard"
+//   - 运行地址
+func githubRunInfo() (string, string) {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	serverURL, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_RUN_ID")
+	if repository == "" || serverURL == "" || runID == "" {
+		return repository, ""
+	}
+	return repository, serverURL + "/" + repository + "/actions/runs/" + runID
+}
+
+// 生成 unified diff（上下文 3 行）
+//
+// 参数:
+//   - [filename] 文件名
+//   - [a]        原内容
+//   - [b]        新内容
+//
+// 返回值:
+//   - unified diff 文本，内容相同时为空
+func unifiedDiff(filename string, a []byte, b []byte) string {
+	const context = 3
+	oldLines := splitLines(string(a))
+	newLines := splitLines(string(b))
+
+	// 去除公共前后缀，仅对变化区域做 LCS
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(oldLines) && prefix == len(newLines) {
+		return ""
+	}
+	x := oldLines[prefix : len(oldLines)-suffix]
+	y := newLines[prefix : len(newLines)-suffix]
+
+	// lcs[i][j]：x[i:] 与 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 编辑序列：' ' 相同，'-' 删除，'+' 新增
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	lines := make([]diffLine, 0, len(oldLines)+len(y))
+	for _, line := range oldLines[:prefix] {
+		lines = append(lines, diffLine{' ', line})
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, diffLine{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', y[j]})
+			j++
+		}
+	}
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	out := strings.Builder{}
+	out.WriteString("--- " + filename + "\n")
+	out.WriteString("+++ " + filename + "\n")
+	for start := 0; start < len(lines); {
+		// 查找下一处变化
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// 合并间隔不超过 2*context 的变化为一个 hunk
+		end := start
+		for k := start; k < len(lines) && k-end <= 2*context; k++ {
+			if lines[k].op != ' ' {
+				end = k + 1
+			}
+		}
+		hunkStart := max(start-context, 0)
+		hunkEnd := min(end+context, len(lines))
+
+		oldStart, newStart := 1, 1
+		for _, line := range lines[:hunkStart] {
+			if line.op != '+' {
+				oldStart++
+			}
+			if line.op != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range lines[hunkStart:hunkEnd] {
+			out.WriteByte(line.op)
+			if text, ok := strings.CutSuffix(line.text, "\n"); ok {
+				out.WriteString(text + "\n")
+			} else {
+				out.WriteString(text + "\n\\ No newline at end of file\n")
+			}
+		}
+		start = hunkEnd
+	}
+	return out.String()
+}
 
-	githubBaseInfo, err := getGithubBaseInfo(ctx, client, githubToken, packageInfo.GithubUser, packageInfo.GithubRepo)
-	if err != nil {
-		return err
+// 按行分割（保留换行符）
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
 	}
-	packageInfo.GithubBaseInfo = githubBaseInfo
-
-	githubContributorsInfo, contributorsTotal, err := getGithubContributorsInfo(ctx, client, githubToken, packageInfo.GithubUser, packageInfo.GithubRepo)
-	if err != nil {
-		return err
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
-	packageInfo.GithubContributorsInfo = githubContributorsInfo
-	packageInfo.GithubBaseInfo.ContributorsTotal = contributorsTotal
-	return nil
+	return lines
 }
 
-// 构造 GitHub API 通用请求头
-func githubHeaders(githubToken string) map[string]string {
-	return map[string]string{
-		"Authorization":        "bearer " + githubToken,
-		"Accept":               "application/vnd.github+json",
-		"X-GitHub-Api-Version": "2026-03-10",
+// 创建带超时的共享 HTTP Client。
+//
+// 复用同一个 Client 可共享连接池；
+// Timeout 防止单个请求挂起拖垮整个流程。
+func newHTTPClient() *HTTPClient {
+	hostLimits, _ := parseHostLimits(defaultHostLimits)
+	return &HTTPClient{
+		Client:           &http.Client{Timeout: httpTimeout},
+		RateLimitMaxWait: defaultRateLimitMaxWait,
+		hostLimits:       hostLimits,
+		limiters:         map[string]*hostLimiter{},
 	}
 }
 
-// 获取 Github 基础信息
+// 设置按 host 的限流（覆盖同名 host 的配置），需在发起请求前调用
 //
 // 参数:
-//   - [ctx]         上下文
-//   - [client]      共享 HTTP Client
-//   - [githubToken] Github Token
-//   - [user]        用户
-//   - [repo]        仓库
-//
-// 返回值:
-//   - [GithubBaseInfo] 信息（404 时降级为空）
-func getGithubBaseInfo(ctx context.Context, client *http.Client, githubToken string, user string, repo string) (GithubBaseInfo, error) {
-	printErrTitle := "📦⚠️ GithubBaseInfo: "
-	rawURL := fmt.Sprintf("https://api.github.com/repos/%s/%s", user, repo)
-	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
-	if err != nil {
-		return GithubBaseInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
-	}
-	if status == http.StatusNotFound {
-		return GithubBaseInfo{}, nil // 仓库不存在 -> 降级
+//   - [hostLimits] 按 host 的限流配置，`*` 为其余 host
+func (c *HTTPClient) SetHostLimits(hostLimits map[string]HostLimit) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for host, limit := range hostLimits {
+		c.hostLimits[host] = limit
+		delete(c.limiters, host)
 	}
-	if status != http.StatusOK {
-		return GithubBaseInfo{}, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+}
+
+// 获取 host 对应的限流器（未配置的 host 按 `*` 独立限流）
+func (c *HTTPClient) limiter(host string) *hostLimiter {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if limiter, ok := c.limiters[host]; ok {
+		return limiter
 	}
-	var data GithubBaseInfo
-	if err := json.Unmarshal(body, &data); err != nil {
-		return GithubBaseInfo{}, fmt.Errorf("%s%w", printErrTitle, err)
+	limit, ok := c.hostLimits[host]
+	if !ok {
+		limit = c.hostLimits["*"]
 	}
-	return data, nil
+	limiter := newHostLimiter(limit)
+	c.limiters[host] = limiter
+	return limiter
 }
 
-// 获取 Github 贡献者信息
-//
-// 参数:
-//   - [ctx]         上下文
-//   - [client]      共享 HTTP Client
-//   - [githubToken] Github Token
-//   - [user]        用户
-//   - [repo]        仓库
-//
-// 返回值:
-//   - [GithubContributorsInfo] 贡献者列表（前 3 位非 Bot）
-//   - 贡献者总数（最多 100；404/204 时为 0）
-func getGithubContributorsInfo(ctx context.Context, client *http.Client, githubToken string, user string, repo string) ([]GithubContributorsInfo, int, error) {
-	printErrTitle := "📦⚠️ GithubContributorsInfo: "
-	rawURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contributors?page=1&per_page=100", user, repo)
-	body, status, err := httpGetWithRetry(ctx, client, rawURL, githubHeaders(githubToken))
-	if err != nil {
-		return nil, 0, fmt.Errorf("%s%w", printErrTitle, err)
+// 创建单个 host 的限流器
+func newHostLimiter(limit HostLimit) *hostLimiter {
+	limiter := &hostLimiter{semaphore: make(chan struct{}, max(limit.Concurrency, 1))}
+	if limit.RequestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
 	}
-	// 404（仓库不存在）/ 204（空仓库，无贡献者）-> 降级
-	if status == http.StatusNotFound || status == http.StatusNoContent {
-		return nil, 0, nil
+	return limiter
+}
+
+// 获取请求许可：先占用并发名额，再等待至允许发起请求的时间；等待期间响应 ctx 取消。
+// 成功后需调用 [hostLimiter.release] 归还并发名额。
+func (l *hostLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	if status != http.StatusOK {
-		return nil, 0, fmt.Errorf("%s%s/%s: unexpected status %d", printErrTitle, user, repo, status)
+	if l.interval <= 0 {
+		return nil
 	}
-	var data []GithubContributorsInfo
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, 0, fmt.Errorf("%s%w", printErrTitle, err)
+
+	l.mutex.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
 	}
+	l.next = start.Add(l.interval)
+	l.mutex.Unlock()
 
-	githubContributorsInfo := []GithubContributorsInfo{}
-	i := 1
-	// 取前 3 位非 Bot 贡献者
-	for _, value := range data {
-		if i > 3 {
-			break
-		}
-		if value.Type == "User" {
-			githubContributorsInfo = append(githubContributorsInfo, value)
-			i++
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.release()
+			return ctx.Err()
 		}
 	}
-	return githubContributorsInfo, len(data), nil
+	return nil
 }
 
-// 匹配 github.com/ 之后的 user/repo 路径。
-// `.` 已转义，避免误匹配 githubXcom 等相似域名。
-var githubURLRegexp = regexp.MustCompile(`github\.com/(.+)`)
-
-// 格式化 Github 信息
-//
-// 参数:
-//   - [value] Github 链接
-//
-// 返回值:
-//   - githubUser 信息
-//   - githubRepo 信息
-func formatGithubInfo(value string) (string, string) {
-	var githubUser, githubRepo string
-	result := githubURLRegexp.FindStringSubmatch(value)
-	if len(result) >= 2 {
-		info := strings.Split(result[1], "/")
-		if len(info) >= 2 && info[0] != "" && info[1] != "" {
-			githubUser = info[0]
-			repo := info[1]
-			// 去除 query/fragment 尾巴（如 ?tab=、#readme）及 .git 后缀
-			if i := strings.IndexAny(repo, "#?"); i >= 0 {
-				repo = repo[:i]
-			}
-			githubRepo = strings.TrimSuffix(repo, ".git")
-		}
-	}
-	return githubUser, githubRepo
+// 归还并发名额
+func (l *hostLimiter) release() {
+	<-l.semaphore
 }
 
-// 对 [packageInfoList] 排序
+// 创建磁盘 HTTP 缓存
 //
 // 参数:
-//   - [packageInfoList]  信息列表
-//   - [sortField]        排序字段 可选：name(default) | published | pubLikes | pubDownloads | githubStars
-//   - [sortMode]         排序方式 可选：asc(default) | desc
-func sortPackageInfo(packageInfoList []PackageInfo, sortField string, sortMode string) {
-	isDesc := sortMode == "desc"
-	sort.SliceStable(packageInfoList, func(i, j int) bool {
-		p1 := packageInfoList[i]
-		p2 := packageInfoList[j]
-		var result bool
-		switch sortField {
-		case "name":
-			// 按照 pub 名称排序
-			result = p1.Name < p2.Name
-		case "published":
-			// 按 pub 最新发布时间排序
-			result = p1.Published > p2.Published
-		case "pubLikes":
-			// 按 pub likes 排序
-			result = p1.ScoreInfo.LikeCount < p2.ScoreInfo.LikeCount
-		case "pubDownloads":
-			// 按 pub downloads 排序
-			result = p1.ScoreInfo.DownloadCount30Days < p2.ScoreInfo.DownloadCount30Days
-		case "githubStars":
-			// 按 github stars 排序
-			result = p1.GithubBaseInfo.StargazersCount < p2.GithubBaseInfo.StargazersCount
-		default:
-			result = p1.Name < p2.Name
-		}
-		if isDesc {
-			return !result
-		}
-		return result
-	})
+//   - [dir] 缓存目录（不存在时自动创建）
+//   - [ttl] 按接口类型的新鲜期
+func newHTTPCache(dir string, ttl map[string]time.Duration) (*HTTPCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("💾❌ HTTPCache: %w", err)
+	}
+	return &HTTPCache{Dir: dir, TTL: ttl}, nil
 }
 
-// 组装表格内容
+// 请求凭据的哈希，用作缓存键的一部分（不落盘原始 token）
 //
 // 参数:
-//   - [packageInfoList]  信息列表
-//   - [sortField]        排序字段 可选：name(default) | published | pubLikes | pubDownloads | githubStars
+//   - [headers] 请求头
 //
 // 返回值:
-//   - markdown 表格内容
-func assembleMarkdownTable(packageInfoList []PackageInfo, sortField string) string {
-	markdownTableList := []MarkdownTable{}
-	for _, value := range packageInfoList {
-		var name, version, platform, licenseName, published,
-			githubStars, pubLikes, pubPoints, pubDownloadCount30Days,
-			issues, pullRequests, contributors string
-		switch value.Code {
-		case 0:
-			// 无法获取信息
-			name = value.Name + " ⁉️"
-		case 1:
-			// 已获取信息
-			// Base
-			const downloadIcon = "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZpZXdCb3g9IjAgMCAyNCAyNCIgZmlsbD0icmdiYSgyNTUsMjU1LDI1NSwxKSI+PHBhdGggZmlsbD0ibm9uZSIgZD0iTTAgMGgyNHYyNEgweiI+PC9wYXRoPjxwYXRoIGQ9Ik0zIDE5SDIxVjIxSDNWMTlaTTEzIDEzLjE3MTZMMTkuMDcxMSA3LjEwMDVMMjAuNDg1MyA4LjUxNDcyTDEyIDE3TDMuNTE0NzIgOC41MTQ3Mkw0LjkyODkzIDcuMTAwNUwxMSAxMy4xNzE2VjJIMTNWMTMuMTcxNloiPjwvcGF0aD48L3N2Zz4="
-			const pointIcon = "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZpZXdCb3g9IjAgMCAyNCAyNCIgZmlsbD0icmdiYSgyNTUsMjU1LDI1NSwxKSI+PHBhdGggZmlsbD0ibm9uZSIgZD0iTTAgMGgyNHYyNEgweiI+PC9wYXRoPjxwYXRoIGQ9Ik0yMyAxMkwxNS45Mjg5IDE5LjA3MTFMMTQuNTE0NyAxNy42NTY5TDIwLjE3MTYgMTJMMTQuNTE0NyA2LjM0MzE3TDE1LjkyODkgNC45Mjg5NkwyMyAxMlpNMy44Mjg0MyAxMkw5LjQ4NTI4IDE3LjY1NjlMOC4wNzEwNyAxOS4wNzExTDEgMTJMOC4wNzEwNyA0LjkyODk2TDkuNDg1MjggNi4zNDMxN0wzLjgyODQzIDEyWiI+PC9wYXRoPjwvc3ZnPg=="
-
-			name = "[" + value.Name + "](https://pub.dev/packages/" + value.Name + ")"
-			version = "v" + value.Version
-			platform = "<strong>Platform:</strong> "
-			if len(value.ScoreInfo.TagsPlatform) > 0 {
-				platform += strings.Join(value.ScoreInfo.TagsPlatform, ", ")
-			} else {
-				platform += "-"
-			}
-			published = "<strong>Published:</strong> " + value.Published
-			githubStars = ""
-			pubLikes = "[![Pub likes](https://img.shields.io/pub/likes/" + value.Name + "?style=social&logo=flutter&logoColor=168AFD&label=)](https://pub.dev/packages/" + value.Name + ")"
-			pubPoints = "[![Pub points](https://img.shields.io/pub/points/" + value.Name + "?style=flat&label=&logo=" + pointIcon + ")](https://pub.dev/packages/" + value.Name + "/score)"
-			pubDownloadCount30Days = "[![Pub downloads](https://img.shields.io/badge/" + formatDownloadCount(value.ScoreInfo.DownloadCount30Days) + url.PathEscape("/") + "month-4AC51C?style=flat&logo=" + downloadIcon + ")](https://pub.dev/packages/" + value.Name + ")"
-			issues = "-"
-			pullRequests = "-"
-
-			// Github
-			if value.GithubUser != "" && value.GithubRepo != "" {
-				githubURL := value.GithubUser + "/" + value.GithubRepo
-				licenseName = "<strong>License:</strong> "
-				if value.GithubBaseInfo.License.Name != "" {
-					licenseName += value.GithubBaseInfo.License.Name
-				} else {
-					licenseName += "-"
-				}
-				githubStars = "[![GitHub stars](https://img.shields.io/github/stars/" + githubURL + "?style=social&logo=github&logoColor=1F2328&label=)](https://github.com/" + githubURL + ")"
-				issues = "[![GitHub issues](https://img.shields.io/github/issues/" + githubURL + "?label=)](https://github.com/" + githubURL + "/issues)"
-				pullRequests = "[![GitHub pull requests](https://img.shields.io/github/issues-pr/" + githubURL + "?label=)](https://github.com/" + githubURL + "/pulls)"
-
-				// contributors begin
-				if len(value.GithubContributorsInfo) > 0 {
-					var githubContributorsInfoList = value.GithubContributorsInfo
-					contributors += `<table align="center" border="0">`
-
-					// contributors
-					switch len(value.GithubContributorsInfo) {
-					case 1:
-						contributors += `<tr align="center">`
-						contributors += `<td>`
-						contributors += `<a href="` + githubContributorsInfoList[0].HtmlUrl + `"><img width="36px" src="` + getGithubAvatarUrl(githubContributorsInfoList[0].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `</tr>`
-					case 2:
-						contributors += `<tr align="center">`
-						contributors += `<td>`
-						contributors += `<a href="` + githubContributorsInfoList[0].HtmlUrl + `"><img width="30px" src="` + getGithubAvatarUrl(githubContributorsInfoList[0].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `<td>`
-						contributors += `<a href="` + githubContributorsInfoList[1].HtmlUrl + `"><img width="30px" src="` + getGithubAvatarUrl(githubContributorsInfoList[1].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `</tr>`
-					case 3:
-						contributors += `<tr align="center">`
-						contributors += `<td colspan="2">`
-						contributors += `<a href="` + githubContributorsInfoList[0].HtmlUrl + `"><img width="36px" src="` + getGithubAvatarUrl(githubContributorsInfoList[0].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `</tr>`
-						contributors += `<tr align="center">`
-						contributors += `<td>`
-						contributors += `<a href="` + githubContributorsInfoList[1].HtmlUrl + `"><img width="30px" src="` + getGithubAvatarUrl(githubContributorsInfoList[1].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `<td>`
-						contributors += `<a href="` + githubContributorsInfoList[2].HtmlUrl + `"><img width="30px" src="` + getGithubAvatarUrl(githubContributorsInfoList[2].Id) + `" /></a>`
-						contributors += `</td>`
-						contributors += `</tr>`
-					}
+//   - Authorization 请求头的 sha256，未携带时为空
+func cacheCredential(headers map[string]string) string {
+	auth := headers["Authorization"]
+	if auth == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(auth))
+	return hex.EncodeToString(sum[:])
+}
 
-					// total
-					contributors += `<tr align="center">`
-					contributors += `<td colspan="2">`
-					if value.GithubBaseInfo.ContributorsTotal >= 100 {
-						contributors += `<a href="https://github.com/` + githubURL + `/graphs/contributors">Total: 99+</a>`
-					} else {
-						contributors += `<a href="https://github.com/` + githubURL + `/graphs/contributors">Total: ` + strconv.Itoa(value.GithubBaseInfo.ContributorsTotal) + `</a>`
-					}
-					contributors += `</td>`
-					contributors += `</tr>`
+// 缓存条目的文件路径
+func (c *HTTPCache) path(rawURL string, credential string) string {
+	sum := sha256.Sum256([]byte(credential + "\n" + rawURL))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
 
-					contributors += `</table>`
-				}
-				// contributors end
-			}
-		}
-		markdownTableList = append(
-			markdownTableList,
-			MarkdownTable{
-				Name:                   name,
-				Version:                version,
-				Description:            value.Description,
-				LicenseName:            licenseName,
-				Platform:               platform,
-				Published:              published,
-				GithubStars:            githubStars,
-				PubLikes:               pubLikes,
-				PubPoints:              pubPoints,
-				PubDownloadCount30Days: pubDownloadCount30Days,
-				Issues:                 issues,
-				PullRequests:           pullRequests,
-				Contributors:           contributors,
-			},
-		)
+// 读取缓存条目（不存在、损坏或凭据不符时返回 nil）
+func (c *HTTPCache) load(rawURL string, credential string) *httpCacheEntry {
+	data, err := os.ReadFile(c.path(rawURL, credential))
+	if err != nil {
+		return nil
 	}
-
-	markdown := ""
-	markdown += "<sub>Sort by " + sortField + " | Total " + strconv.Itoa(len(markdownTableList)) + "</sub> \n\n" +
-		"| <sub>Package</sub> | <sub>Stars/Likes</sub> | <sub>Downloads/Points</sub> | <sub>Issues / Pull_requests</sub> | <sub>Contributors</sub> | \n" +
-		"|--------------------|------------------------|------------------------------|-----------------------------------|:-----------------------:| \n"
-	for _, value := range markdownTableList {
-		markdown += "" +
-			"| " + value.Name + " <sup><strong>" + value.Version + "</strong></sup> <br/> <sub>" + formatString(value.Description) + "</sub> <br/> <sub>" + value.LicenseName + "</sub> <br/> <sub>" + value.Platform + "</sub> <br/> " + "<sub>" + value.Published + "</sub>" +
-			" | " + value.GithubStars + " <br/> " + value.PubLikes +
-			" | " + value.PubDownloadCount30Days + " <br/> " + value.PubPoints +
-			" | " + value.Issues + " <br/> " + value.PullRequests +
-			" | " + value.Contributors +
-			" | \n"
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL || entry.Credential != credential {
+		return nil
 	}
-	return markdown
+	return &entry
 }
 
-// 更新 Markdown 表格
-//
-// 识别：<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->
-//
-// 参数:
-//   - [filename] 更新的文件
-//   - [markdown] 更新内容
-func updateMarkdownTable(filename string, markdown string) error {
-	md, err := os.ReadFile(filename)
+// 写入缓存条目（先写临时文件再重命名，避免并发读到不完整内容）。
+// 缓存仅用于加速，写入失败只打印警告。
+func (c *HTTPCache) store(entry *httpCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = writeFileAtomic(c.path(entry.URL, entry.Credential), data, 0600)
+	}
 	if err != nil {
-		return fmt.Errorf("📄❌ updateMarkdownTable: Error reade a file: %w", err)
+		fmt.Println("💾⚠️ HTTPCache:", err)
 	}
+}
 
-	begin := "<!-- md:PubDashboard begin -->"
-	end := "<!-- md:PubDashboard end -->"
-	newMdText := bytes.NewBuffer(nil)
-	newMdText.WriteString(begin)
-	newMdText.WriteString(" \n")
-	newMdText.WriteString(markdown)
-	newMdText.WriteString(" \n")
-	newMdText.WriteString("Updated on " + time.Now().Format(time.RFC3339) + " by [Action](https://github.com/AmosHuKe/pub-dashboard). \n")
-	newMdText.WriteString(end)
+// 缓存条目转换为响应
+func (entry *httpCacheEntry) response() httpResponse {
+	header := http.Header{}
+	if entry.Link != "" {
+		header.Set("Link", entry.Link)
+	}
+	return httpResponse{Body: entry.Body, Status: http.StatusOK, Header: header}
+}
 
-	reg := regexp.MustCompile(begin + "(?s)(.*?)" + end)
-	newMd := reg.ReplaceAll(md, newMdText.Bytes())
+// 缓存条目是否仍在新鲜期内
+func (c *HTTPCache) fresh(entry *httpCacheEntry, now time.Time) bool {
+	ttl := c.TTL[cacheEndpointType(entry.URL)]
+	return ttl > 0 && now.Sub(entry.StoredAt) < ttl
+}
 
-	err = os.WriteFile(filename, newMd, 0644)
+// 识别请求地址的接口类型，用于按类型设置缓存新鲜期
+//
+// 参数:
+//   - [rawURL] 请求地址
+//
+// 返回值:
+//   - package（package 元数据）| score（评分）| search（publisher 搜索）| github | other
+func cacheEndpointType(rawURL string) string {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("📄❌ updateMarkdownTable: Error writing a file: %w", err)
+		return "other"
 	}
-	fmt.Println("📄✅ updateMarkdownTable: Success")
-	return nil
+	switch {
+	case strings.Contains(u.Path, "/repos/"):
+		return "github"
+	case strings.HasSuffix(u.Path, "/api/search"):
+		return "search"
+	case strings.Contains(u.Path, "/api/packages/") && strings.HasSuffix(u.Path, "/score"):
+		return "score"
+	case strings.Contains(u.Path, "/api/packages/"):
+		return "package"
+	}
+	return "other"
 }
 
-// 更新 Markdown Package 总数计数
-//
-// 识别：<!-- md:PubDashboard-total begin --><!-- md:PubDashboard-total end -->
+// 解析按接口类型的缓存新鲜期
 //
 // 参数:
-//   - [filename] 更新的文件
-//   - [total]    总数
-func updateMarkdownPackageTotal(filename string, total int) error {
-	md, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("📄❌ updateMarkdownPackageTotal: Error reade a file: %w", err)
+//   - [value] 新鲜期配置（`,`逗号分割），例如："package=6h,score=1h,search=1h,github=0s"
+//
+// 返回值:
+//   - 按接口类型的新鲜期
+func parseCacheTTL(value string) (map[string]time.Duration, error) {
+	ttl := map[string]time.Duration{}
+	for _, item := range removeDuplicates(strings.Split(value, ",")) {
+		endpointType, duration, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(endpointType) == "" {
+			return nil, fmt.Errorf("invalid cache ttl %q, want type=duration", item)
+		}
+		durationValue, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || durationValue < 0 {
+			return nil, fmt.Errorf("invalid cache ttl %q: %v", item, err)
+		}
+		ttl[strings.TrimSpace(endpointType)] = durationValue
 	}
+	return ttl, nil
+}
 
-	begin := "<!-- md:PubDashboard-total begin -->"
-	end := "<!-- md:PubDashboard-total end -->"
-	newMdText := bytes.NewBuffer(nil)
-	newMdText.WriteString(begin)
-	newMdText.WriteString(strconv.Itoa(total))
-	newMdText.WriteString(end)
-
-	reg := regexp.MustCompile(begin + "(?s)(.*?)" + end)
-	newMd := reg.ReplaceAll(md, newMdText.Bytes())
-
-	err = os.WriteFile(filename, newMd, 0644)
+// 原子写入文件：写入同目录下的临时文件后重命名，避免中途失败留下不完整的文件
+//
+// 参数:
+//   - [filename] 文件名
+//   - [data]     文件内容
+//   - [perm]     文件权限
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("📄❌ updateMarkdownPackageTotal: Error writing a file: %w", err)
+		return err
 	}
-	fmt.Println("📄✅ updateMarkdownPackageTotal: Success")
-	return nil
+	tempName := file.Name()
+	defer os.Remove(tempName) // 重命名成功后为空操作
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempName, filename)
 }
 
-// 创建带超时的共享 HTTP Client。
+// 解析按 host 的限流配置
 //
-// 复用同一个 Client 可共享连接池；
-// Timeout 防止单个请求挂起拖垮整个流程。
-func newHTTPClient() *http.Client {
-	return &http.Client{Timeout: httpTimeout}
+// 参数:
+//   - [value] 限流配置（`,`逗号分割），例如："pub.dev=8:10,api.github.com=6:10,*=4:5"
+//
+// 返回值:
+//   - 按 host 的限流配置
+func parseHostLimits(value string) (map[string]HostLimit, error) {
+	hostLimits := map[string]HostLimit{}
+	for _, item := range removeDuplicates(strings.Split(value, ",")) {
+		host, limit, ok := strings.Cut(item, "=")
+		concurrency, rps, ok2 := strings.Cut(limit, ":")
+		if !ok || !ok2 || strings.TrimSpace(host) == "" {
+			return nil, fmt.Errorf("invalid host limit %q, want host=concurrency:rps", item)
+		}
+		concurrencyValue, err := strconv.Atoi(strings.TrimSpace(concurrency))
+		if err != nil || concurrencyValue < 1 {
+			return nil, fmt.Errorf("invalid host limit %q: concurrency must be a positive integer", item)
+		}
+		rpsValue, err := strconv.ParseFloat(strings.TrimSpace(rps), 64)
+		if err != nil || rpsValue < 0 {
+			return nil, fmt.Errorf("invalid host limit %q: rps must be a non-negative number", item)
+		}
+		hostLimits[strings.ToLower(strings.TrimSpace(host))] = HostLimit{Concurrency: concurrencyValue, RequestsPerSecond: rpsValue}
+	}
+	return hostLimits, nil
 }
 
 // concurrentMap 以 [concurrency] 为上限并发地将 fn 应用到每个 item，
@@ -844,9 +5268,17 @@ func concurrentMap[T any, R any](ctx context.Context, items []T, concurrency int
 
 // 带重试的 HTTP GET 请求
 //
-// 对传输层错误、429 (Too Many Requests)、403 (限流/鉴权)、5xx 进行指数退避重试，
+// 启用磁盘缓存（[HTTPClient.Cache]）时，新鲜期内直接返回缓存；
+// 否则携带 ETag / Last-Modified 发起条件请求，304 时返回缓存内容（状态码按 200 返回）。
+//
+// 每次尝试前按请求 host 限流（并发数 + 每秒请求数），退避等待期间不占用并发名额。
+// 对传输层错误、429 (Too Many Requests)、限流导致的 403、5xx 进行重试，
 // 最多尝试 [maxAttempts] 次；退避期间响应 ctx 取消。
 //
+// 限流时优先按 Retry-After / X-RateLimit-Reset 等待至重置，
+// 等待时长超过 [HTTPClient.RateLimitMaxWait] 则直接返回 [errRateLimited]；
+// 无限流特征的 403（权限不足等）不重试，直接交由调用方处理。
+//
 // 仅负责传输 + 重试瞬时故障，状态码的业务语义（如 404 的含义）
 // 由调用方根据返回的 status 自行解释。
 //
@@ -860,58 +5292,223 @@ func concurrentMap[T any, R any](ctx context.Context, items []T, concurrency int
 //   - 响应体
 //   - HTTP 状态码
 //   - 错误（传输层彻底失败或重试耗尽时非 nil）
-func httpGetWithRetry(ctx context.Context, client *http.Client, rawURL string, headers map[string]string) ([]byte, int, error) {
+func httpGetWithRetry(ctx context.Context, client *HTTPClient, rawURL string, headers map[string]string) ([]byte, int, error) {
+	res, err := httpGetResponse(ctx, client, rawURL, headers)
+	return res.Body, res.Status, err
+}
+
+// 带重试的 HTTP GET 请求，同 [httpGetWithRetry]，额外返回响应头
+//
+// 命中缓存时响应头仅包含缓存的 Link
+//
+// 参数:
+//   - [ctx]     上下文
+//   - [client]  共享 HTTP Client
+//   - [rawURL]  请求地址
+//   - [headers] 附加请求头（可为 nil）
+//
+// 返回值:
+//   - [httpResponse] 响应
+//   - 错误（传输层彻底失败或重试耗尽时非 nil）
+func httpGetResponse(ctx context.Context, client *HTTPClient, rawURL string, headers map[string]string) (httpResponse, error) {
+	return httpDoResponse(ctx, client, http.MethodGet, rawURL, headers, nil)
+}
+
+// 带重试的 HTTP POST 请求，退避、限流与重试同 [httpGetWithRetry]，不使用缓存
+//
+// 5xx 重试时可能重复投递，接收方需自行去重
+//
+// 参数:
+//   - [ctx]     上下文
+//   - [client]  共享 HTTP Client
+//   - [rawURL]  请求地址
+//   - [headers] 附加请求头（可为 nil）
+//   - [body]    请求体
+//
+// 返回值:
+//   - 响应体
+//   - HTTP 状态码
+//   - 错误（传输层彻底失败或重试耗尽时非 nil）
+func httpPostWithRetry(ctx context.Context, client *HTTPClient, rawURL string, headers map[string]string, body []byte) ([]byte, int, error) {
+	res, err := httpDoResponse(ctx, client, http.MethodPost, rawURL, headers, body)
+	return res.Body, res.Status, err
+}
+
+// 带重试的 HTTP 请求（见 [httpGetWithRetry]），仅 GET 请求使用缓存
+//
+// 参数:
+//   - [ctx]     上下文
+//   - [client]  共享 HTTP Client
+//   - [method]  请求方法
+//   - [rawURL]  请求地址
+//   - [headers] 附加请求头（可为 nil）
+//   - [body]    请求体（可为 nil），每次尝试重新发送
+//
+// 返回值:
+//   - [httpResponse] 响应
+//   - 错误（传输层彻底失败或重试耗尽时非 nil）
+func httpDoResponse(ctx context.Context, client *HTTPClient, method string, rawURL string, headers map[string]string, body []byte) (httpResponse, error) {
+	cache := client.Cache
+	if method != http.MethodGet {
+		cache = nil
+	}
+	var cached *httpCacheEntry
+	credential := cacheCredential(headers)
+	if cache != nil {
+		cached = cache.load(rawURL, credential)
+		if cached != nil && cache.fresh(cached, time.Now()) {
+			return cached.response(), nil
+		}
+	}
+
 	var lastErr error
+	// 限流要求的等待时长，<0 表示未知（按指数退避）
+	rateLimitWait := time.Duration(-1)
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		// 退避（首次不等待）：500ms, 1s, 2s ...
+		// 退避（首次不等待）：500ms, 1s, 2s ...，限流时等待至重置
 		if attempt > 1 {
 			delay := retryBaseDelay * time.Duration(1<<(attempt-2))
+			if rateLimitWait >= 0 {
+				delay = rateLimitWait
+			}
+			rateLimitWait = -1
 			select {
 			case <-ctx.Done():
-				return nil, 0, ctx.Err()
+				return httpResponse{}, ctx.Err()
 			case <-time.After(delay):
 			}
 		}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
 		if err != nil {
-			return nil, 0, err // 构造请求失败不可恢复
+			return httpResponse{}, err // 构造请求失败不可恢复
 		}
 		for key, value := range headers {
 			req.Header.Set(key, value)
 		}
+		if cached != nil {
+			// 条件请求
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
 
+		limiter := client.limiter(strings.ToLower(req.URL.Hostname()))
+		if err := limiter.acquire(ctx); err != nil {
+			return httpResponse{}, err
+		}
 		res, err := client.Do(req)
 		if err != nil {
+			limiter.release()
 			if ctx.Err() != nil {
-				return nil, 0, ctx.Err() // 已取消则立即返回
+				return httpResponse{}, ctx.Err() // 已取消则立即返回
 			}
 			lastErr = err
 			continue
 		}
 
-		body, readErr := io.ReadAll(res.Body)
+		resBody, readErr := io.ReadAll(res.Body)
 		res.Body.Close()
+		limiter.release()
 		status := res.StatusCode
 
 		if readErr != nil {
 			if ctx.Err() != nil {
-				return nil, status, ctx.Err()
+				return httpResponse{Status: status}, ctx.Err()
 			}
 			lastErr = readErr
 			continue
 		}
 
-		// 可重试的状态码：限流与服务端错误
-		if status == http.StatusTooManyRequests || status == http.StatusForbidden || status >= 500 {
+		// 限流：等待至重置（超出等待上限则直接失败）
+		if status == http.StatusTooManyRequests || status == http.StatusForbidden {
+			wait, limited := rateLimitDelay(status, res.Header, resBody, time.Now())
+			if !limited {
+				return httpResponse{Body: resBody, Status: status, Header: res.Header}, nil // 权限不足等真实的 403，重试无意义
+			}
+			if wait > client.RateLimitMaxWait {
+				return httpResponse{Status: status}, fmt.Errorf("%w: status %d, resets in %s, exceeds wait budget %s", errRateLimited, status, wait.Round(time.Second), client.RateLimitMaxWait)
+			}
+			rateLimitWait = wait
+			lastErr = fmt.Errorf("%w: status %d", errRateLimited, status)
+			continue
+		}
+
+		// 可重试的状态码：服务端错误（501 表示接口未实现，重试无意义）
+		if status >= 500 && status != http.StatusNotImplemented {
 			lastErr = fmt.Errorf("unexpected status %d", status)
 			continue
 		}
 
+		if cache != nil {
+			// 未变化：复用缓存并刷新新鲜期
+			if status == http.StatusNotModified && cached != nil {
+				cached.StoredAt = time.Now()
+				cache.store(cached)
+				return cached.response(), nil
+			}
+			if status == http.StatusOK {
+				cache.store(&httpCacheEntry{
+					URL:          rawURL,
+					Credential:   credential,
+					ETag:         res.Header.Get("ETag"),
+					LastModified: res.Header.Get("Last-Modified"),
+					Link:         res.Header.Get("Link"),
+					StoredAt:     time.Now(),
+					Body:         resBody,
+				})
+			}
+		}
+
 		// 成功或不可重试的状态码（2xx、404 等），交由调用方判断
-		return body, status, nil
+		return httpResponse{Body: resBody, Status: status, Header: res.Header}, nil
 	}
-	return nil, 0, fmt.Errorf("After %d attempts: %w", maxAttempts, lastErr)
+	return httpResponse{}, fmt.Errorf("After %d attempts: %w", maxAttempts, lastErr)
+}
+
+// 解析限流响应需要等待的时长
+//
+// 依次识别：
+//   - Retry-After（秒数或 HTTP 日期）
+//   - X-RateLimit-Remaining 为 0 时的 X-RateLimit-Reset（Unix 秒）
+//   - 响应体中的 "rate limit" 字样（GitHub 二级限流）
+//   - 429 状态码本身
+//
+// 参数:
+//   - [status] HTTP 状态码（403 / 429）
+//   - [header] 响应头
+//   - [body]   响应体
+//   - [now]    当前时间
+//
+// 返回值:
+//   - 需要等待的时长，<0 表示未知（按指数退避）
+//   - 是否为限流
+func rateLimitDelay(status int, header http.Header, body []byte, now time.Time) (time.Duration, bool) {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+		return -1, true
+	}
+	if bytes.Contains(bytes.ToLower(body), []byte("rate limit")) {
+		return -1, true
+	}
+	return -1, status == http.StatusTooManyRequests
 }
 
 // 由于直接获取 GithubContributorsInfo.AvatarUrl 有可能会是私有头像地址，
@@ -937,7 +5534,7 @@ func formatString(v string) string {
 	return value
 }
 
-// 格式化下载数量（便于展示）
+// 格式化下载数量（便于展示，[defaultLocale] 格式）
 //
 // 参数:
 //   - [num] 需要格式化的数量
@@ -945,22 +5542,7 @@ func formatString(v string) string {
 // 返回值:
 //   - 格式化后的数量字符
 func formatDownloadCount(num int) string {
-	var formatted string
-	var suffix string
-
-	if num >= 1000000 {
-		formatted = fmt.Sprintf("%.2f", float64(num)/1000000)
-		suffix = "M"
-	} else if num >= 1000 {
-		formatted = fmt.Sprintf("%.2f", float64(num)/1000)
-		suffix = "k"
-	} else {
-		return strconv.Itoa(num)
-	}
-
-	// 去掉多余的0和小数点
-	formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
-	return formatted + suffix
+	return locales[defaultLocale].formatCount(num)
 }
 
 // 去重并保持首次出现的顺序，同时去除首尾空白与空字符串
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -63,10 +73,11 @@ func TestFormatGithubInfo(t *testing.T) {
 		{"non-github url", "https://pub.dev/packages/flutter_tilt", "", ""},
 		{"empty", "", "", ""},
 		{"lookalike domain is not github", "https://githubXcom/evil/repo", "", ""},
+		{"enterprise host is not github.com", "https://ghe.example.com/org/repo", "", ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user, repo := formatGithubInfo(tt.in)
+			user, repo, _, _ := formatGithubInfo(tt.in, defaultGithubURL)
 			if user != tt.wantUser || repo != tt.wantRepo {
 				t.Errorf("formatGithubInfo(%q) = (%q, %q), want (%q, %q)", tt.in, user, repo, tt.wantUser, tt.wantRepo)
 			}
@@ -165,6 +176,18 @@ func TestSortPackageInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("by githubPullRequests desc", func(t *testing.T) {
+		list := []PackageInfo{
+			{Name: "a", GithubOpenPullRequests: 2},
+			{Name: "b", GithubOpenPullRequests: 30},
+			{Name: "c", GithubOpenPullRequests: 5},
+		}
+		sortPackageInfo(list, "githubPullRequests", "desc")
+		if got := names(list); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+			t.Errorf("got %v", got)
+		}
+	})
+
 	t.Run("stable for equal values", func(t *testing.T) {
 		// All stars equal -> input order must be preserved (deterministic output).
 		list := []PackageInfo{
@@ -300,6 +323,106 @@ func TestHTTPGetWithRetry(t *testing.T) {
 			t.Errorf("Authorization header = %q, want %q", gotAuth, "bearer xyz")
 		}
 	})
+
+	t.Run("403 without rate limit is not retried", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+		}))
+		defer srv.Close()
+
+		_, status, err := httpGetWithRetry(context.Background(), client, srv.URL, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", status)
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("hits = %d, want 1 (permission 403 must not retry)", n)
+		}
+	})
+
+	t.Run("fails fast when reset exceeds wait budget", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer srv.Close()
+
+		_, _, err := httpGetWithRetry(context.Background(), client, srv.URL, nil)
+		if !errors.Is(err, errRateLimited) {
+			t.Fatalf("err = %v, want errRateLimited", err)
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("hits = %d, want 1", n)
+		}
+	})
+
+	t.Run("waits for Retry-After then succeeds", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hits.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		_, status, err := httpGetWithRetry(context.Background(), client, srv.URL, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status != http.StatusOK {
+			t.Errorf("status = %d, want 200", status)
+		}
+		if n := hits.Load(); n != 2 {
+			t.Errorf("hits = %d, want 2", n)
+		}
+	})
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+	tests := []struct {
+		name        string
+		status      int
+		header      http.Header
+		body        string
+		wantWait    time.Duration
+		wantLimited bool
+	}{
+		{"retry-after seconds", 429, header("Retry-After", "30"), "", 30 * time.Second, true},
+		{"retry-after date", 403, header("Retry-After", now.Add(time.Minute).UTC().Format(http.TimeFormat)), "", time.Minute, true},
+		{"primary rate limit reset", 403, header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1700000120"), "", 2 * time.Minute, true},
+		{"reset in the past", 403, header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1699999999"), "", 0, true},
+		{"secondary rate limit message", 403, header(), `{"message":"You have exceeded a secondary rate limit"}`, -1, true},
+		{"plain 429", 429, header(), "", -1, true},
+		{"permission 403", 403, header("X-RateLimit-Remaining", "10"), `{"message":"Must have admin rights"}`, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, limited := rateLimitDelay(tt.status, tt.header, []byte(tt.body), now)
+			if wait != tt.wantWait || limited != tt.wantLimited {
+				t.Errorf("rateLimitDelay() = (%v, %v), want (%v, %v)", wait, limited, tt.wantWait, tt.wantLimited)
+			}
+		})
+	}
 }
 
 func TestConcurrentMap(t *testing.T) {
@@ -371,3 +494,1838 @@ func TestConcurrentMap(t *testing.T) {
 		}
 	})
 }
+
+func TestAssembleMarkdownTableGroupByRepo(t *testing.T) {
+	list := []PackageInfo{
+		{Code: 1, Name: "foo", Version: "1.0.0", GithubUser: "org", GithubRepo: "mono", GithubBaseInfo: GithubBaseInfo{StargazersCount: 10}},
+		{Code: 1, Name: "solo", Version: "2.0.0", GithubUser: "org", GithubRepo: "solo"},
+		{Code: 1, Name: "bar", Version: "1.1.0", GithubUser: "Org", GithubRepo: "Mono"},
+		{Code: 0, Name: "missing"},
+	}
+
+	t.Run("ungrouped renders one row per package", func(t *testing.T) {
+		got := assembleMarkdownTable(list, TableOptions{SortField: "name"})
+		if n := strings.Count(got, "https://img.shields.io/github/stars/org/mono?"); n != 1 {
+			t.Errorf("stars badges for org/mono = %d, want 1", n)
+		}
+		if strings.Contains(got, "📁") {
+			t.Errorf("unexpected group header in ungrouped table:\n%s", got)
+		}
+	})
+
+	t.Run("grouped renders shared header and sub-rows", func(t *testing.T) {
+		got := assembleMarkdownTable(list, TableOptions{SortField: "name", GroupByRepo: true})
+		lines := strings.Split(strings.TrimSpace(got), "\n")
+		// Sort line + blank + header + separator + group header + 2 sub-rows + solo + missing
+		if len(lines) != 9 {
+			t.Fatalf("got %d lines, want 9:\n%s", len(lines), got)
+		}
+		if !strings.HasPrefix(lines[4], "| 📁 [org/mono](https://github.com/org/mono)") {
+			t.Errorf("group header = %q", lines[4])
+		}
+		if !strings.Contains(lines[4], "<strong>Packages:</strong> 2") {
+			t.Errorf("group header missing package count: %q", lines[4])
+		}
+		if !strings.HasPrefix(lines[5], "| ↳ [foo]") || !strings.HasPrefix(lines[6], "| ↳ [bar]") {
+			t.Errorf("sub-rows = %q, %q", lines[5], lines[6])
+		}
+		for _, line := range lines[5:7] {
+			if strings.Contains(line, "github/stars") || strings.Contains(line, "github/issues") {
+				t.Errorf("sub-row repeats Github metrics: %q", line)
+			}
+		}
+		if !strings.HasPrefix(lines[7], "| [solo]") || !strings.HasPrefix(lines[8], "| missing ⁉️") {
+			t.Errorf("ungrouped rows = %q, %q", lines[7], lines[8])
+		}
+		if !strings.Contains(got, "Total 4") {
+			t.Errorf("total should count packages, got:\n%s", got)
+		}
+	})
+}
+
+func TestFormatGithubPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantRef  string
+		wantPath string
+	}{
+		{"repo root", "https://github.com/org/repo", "", ""},
+		{"tree without path", "https://github.com/org/repo/tree/main", "", ""},
+		{"tree with path", "https://github.com/org/repo/tree/main/packages/foo", "main", "packages/foo"},
+		{"blob with path", "https://github.com/org/repo/blob/v1/packages/foo/README.md", "v1", "packages/foo/README.md"},
+		{"trailing slash", "https://github.com/org/repo/tree/main/packages/foo/", "main", "packages/foo"},
+		{"with fragment", "https://github.com/org/repo/tree/main/packages/foo#readme", "main", "packages/foo"},
+		{"other sub path", "https://github.com/org/repo/issues/1", "", ""},
+		{"non-github url", "https://pub.dev/packages/flutter_tilt", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, ref, path := formatGithubInfo(tt.in, defaultGithubURL)
+			if ref != tt.wantRef || path != tt.wantPath {
+				t.Errorf("formatGithubInfo(%q) path = (%q, %q), want (%q, %q)", tt.in, ref, path, tt.wantRef, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestAssembleMarkdownTableRowMonorepo(t *testing.T) {
+	value := PackageInfo{Code: 1, Name: "foo", GithubUser: "org", GithubRepo: "repo", GithubRef: "main", GithubPath: "packages/foo"}
+
+	row := assembleMarkdownTableRow(value, TableOptions{})
+	if !strings.Contains(row.GithubStars, "(https://github.com/org/repo/tree/main/packages/foo)") {
+		t.Errorf("stars link should point at the package folder: %q", row.GithubStars)
+	}
+	if !strings.Contains(row.Issues, "(https://github.com/org/repo/issues)") {
+		t.Errorf("issues should not be scoped without a label: %q", row.Issues)
+	}
+
+	row = assembleMarkdownTableRow(value, TableOptions{IssueLabel: "p: {name}"})
+	if !strings.Contains(row.Issues, "https://img.shields.io/github/issues/org/repo/p:%20foo?") {
+		t.Errorf("issues badge should be scoped to the label: %q", row.Issues)
+	}
+	if !strings.Contains(row.PullRequests, "/pulls?q=is%3Apr+is%3Aopen+label%3A%22p%3A+foo%22)") {
+		t.Errorf("pull requests link should be scoped to the label: %q", row.PullRequests)
+	}
+	if row = assembleMarkdownTableRow(value, TableOptions{IssueLabel: "p: {name}", Render: renderNative}); row.Issues != "[Issues](https://github.com/org/repo/issues?q=is%3Aissue+is%3Aopen+label%3A%22p%3A+foo%22)" {
+		t.Errorf("native issues without label counts = %q", row.Issues)
+	}
+
+	// 已获取按 label 过滤的数量时展示数量
+	value.GithubLabelCounts = map[string]GithubOpenCounts{"p: foo": {Issues: 2, PullRequests: 1}}
+	row = assembleMarkdownTableRow(value, TableOptions{IssueLabel: "p: {name}", Render: renderNative})
+	if row.Issues != "[🐞 2](https://github.com/org/repo/issues?q=is%3Aissue+is%3Aopen+label%3A%22p%3A+foo%22)" || !strings.HasPrefix(row.PullRequests, "[🔀 1](") {
+		t.Errorf("native scoped counts = %q, %q", row.Issues, row.PullRequests)
+	}
+	options := TableOptions{IssueLabel: "p: {name}", Render: renderSVG, BadgeURL: "assets"}
+	row = assembleMarkdownTableRow(value, options)
+	if row.Issues != "[![GitHub issues](assets/foo-issues-label.svg)](https://github.com/org/repo/issues?q=is%3Aissue+is%3Aopen+label%3A%22p%3A+foo%22)" {
+		t.Errorf("svg scoped issues = %q", row.Issues)
+	}
+	value.Code = 1
+	badges := collectBadges([]PackageInfo{value}, options)
+	if !bytes.Contains(badges["foo-issues-label.svg"], []byte(">2</text>")) || !bytes.Contains(badges["foo-pulls-label.svg"], []byte(">1</text>")) {
+		t.Errorf("scoped badges = %v", slices.Sorted(maps.Keys(badges)))
+	}
+
+	// 仓库根目录的 package 不按 label 过滤
+	value.GithubRef, value.GithubPath = "", ""
+	row = assembleMarkdownTableRow(value, TableOptions{IssueLabel: "p: {name}"})
+	if !strings.Contains(row.Issues, "(https://github.com/org/repo/issues)") {
+		t.Errorf("root package issues should not be scoped: %q", row.Issues)
+	}
+}
+
+func TestParseHostLimits(t *testing.T) {
+	got, err := parseHostLimits("pub.dev=8:10, API.github.com=2:0.5,*=1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]HostLimit{
+		"pub.dev":        {Concurrency: 8, RequestsPerSecond: 10},
+		"api.github.com": {Concurrency: 2, RequestsPerSecond: 0.5},
+		"*":              {Concurrency: 1, RequestsPerSecond: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, in := range []string{"pub.dev", "pub.dev=8", "=1:1", "pub.dev=0:1", "pub.dev=1:-1", "pub.dev=a:1"} {
+		if _, err := parseHostLimits(in); err == nil {
+			t.Errorf("parseHostLimits(%q): expected error", in)
+		}
+	}
+
+	if got, err := parseHostLimits(""); err != nil || len(got) != 0 {
+		t.Errorf("empty input: got %v, err %v", got, err)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	t.Run("limits concurrent requests per host", func(t *testing.T) {
+		var current, max atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := current.Add(1)
+			for {
+				m := max.Load()
+				if c <= m || max.CompareAndSwap(m, c) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			current.Add(-1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		client := newHTTPClient()
+		client.SetHostLimits(map[string]HostLimit{"127.0.0.1": {Concurrency: 2}})
+		_, err := concurrentMap(context.Background(), make([]int, 12), 12, func(ctx context.Context, _ int) (int, error) {
+			_, _, err := httpGetWithRetry(ctx, client, srv.URL, nil)
+			return 0, err
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m := max.Load(); m > 2 {
+			t.Errorf("max observed concurrency = %d, want <= 2", m)
+		}
+	})
+
+	t.Run("spaces requests by rate", func(t *testing.T) {
+		limiter := newHostLimiter(HostLimit{Concurrency: 10, RequestsPerSecond: 50})
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			if err := limiter.acquire(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			limiter.release()
+		}
+		// 5 次请求之间至少间隔 4 * 20ms
+		if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+			t.Errorf("elapsed = %v, want >= 80ms", elapsed)
+		}
+	})
+
+	t.Run("respects cancelled context", func(t *testing.T) {
+		limiter := newHostLimiter(HostLimit{Concurrency: 1})
+		if err := limiter.acquire(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := limiter.acquire(ctx); err == nil {
+			t.Fatal("expected error for cancelled context while slot is taken")
+		}
+	})
+}
+
+func TestCacheEndpointType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://pub.dev/api/packages/flutter_tilt", "package"},
+		{"https://pub.dev/api/packages/flutter_tilt/score", "score"},
+		{"https://pub.dev/api/search?q=publisher:dart.dev&page=1", "search"},
+		{"https://api.github.com/repos/AmosHuKe/pub-dashboard", "github"},
+		{"https://ghe.example.com/api/v3/repos/org/repo/contributors?per_page=100", "github"},
+		{"https://example.com/other", "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := cacheEndpointType(tt.in); got != tt.want {
+				t.Errorf("cacheEndpointType(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPGetWithRetryCache(t *testing.T) {
+	t.Run("revalidates with ETag and reuses body on 304", func(t *testing.T) {
+		var hits atomic.Int32
+		var gotIfNoneMatch atomic.Value
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			if inm := r.Header.Get("If-None-Match"); inm != "" {
+				gotIfNoneMatch.Store(inm)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("payload"))
+		}))
+		defer srv.Close()
+
+		client := newHTTPClient()
+		cache, err := newHTTPCache(t.TempDir(), map[string]time.Duration{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.Cache = cache
+		rawURL := srv.URL + "/repos/org/repo"
+
+		for i := 0; i < 2; i++ {
+			body, status, err := httpGetWithRetry(context.Background(), client, rawURL, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status != http.StatusOK || string(body) != "payload" {
+				t.Errorf("request %d: status=%d body=%q, want 200/payload", i, status, body)
+			}
+		}
+		if n := hits.Load(); n != 2 {
+			t.Errorf("hits = %d, want 2", n)
+		}
+		if got, _ := gotIfNoneMatch.Load().(string); got != `"v1"` {
+			t.Errorf("If-None-Match = %q, want %q", got, `"v1"`)
+		}
+	})
+
+	t.Run("serves fresh entries without a request", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("score"))
+		}))
+		defer srv.Close()
+
+		client := newHTTPClient()
+		cache, err := newHTTPCache(t.TempDir(), map[string]time.Duration{"score": time.Hour})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.Cache = cache
+		rawURL := srv.URL + "/api/packages/foo/score"
+
+		for i := 0; i < 3; i++ {
+			if body, _, err := httpGetWithRetry(context.Background(), client, rawURL, nil); err != nil || string(body) != "score" {
+				t.Fatalf("request %d: body=%q err=%v", i, body, err)
+			}
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("hits = %d, want 1", n)
+		}
+	})
+
+	t.Run("keys entries by credential", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("auth=" + r.Header.Get("Authorization")))
+		}))
+		defer srv.Close()
+
+		client := newHTTPClient()
+		cache, err := newHTTPCache(t.TempDir(), map[string]time.Duration{"github": time.Hour})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.Cache = cache
+		rawURL := srv.URL + "/repos/org/repo"
+
+		for _, tt := range []struct {
+			headers map[string]string
+			want    string
+		}{
+			{map[string]string{"Authorization": "Bearer a"}, "auth=Bearer a"},
+			{map[string]string{"Authorization": "Bearer b"}, "auth=Bearer b"},
+			{nil, "auth="},
+			{map[string]string{"Authorization": "Bearer a"}, "auth=Bearer a"},
+		} {
+			body, _, err := httpGetWithRetry(context.Background(), client, rawURL, tt.headers)
+			if err != nil || string(body) != tt.want {
+				t.Errorf("headers %v: body=%q err=%v, want %q", tt.headers, body, err, tt.want)
+			}
+		}
+		if n := hits.Load(); n != 3 {
+			t.Errorf("hits = %d, want 3", n)
+		}
+		entries, _ := os.ReadDir(cache.Dir)
+		for _, entry := range entries {
+			data, _ := os.ReadFile(filepath.Join(cache.Dir, entry.Name()))
+			if strings.Contains(string(data), "Bearer") {
+				t.Errorf("cache entry %s stores the raw token", entry.Name())
+			}
+		}
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		client := newHTTPClient()
+		cache, err := newHTTPCache(t.TempDir(), map[string]time.Duration{"package": time.Hour})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.Cache = cache
+		for i := 0; i < 2; i++ {
+			if _, status, _ := httpGetWithRetry(context.Background(), client, srv.URL+"/api/packages/foo", nil); status != http.StatusNotFound {
+				t.Errorf("status = %d, want 404", status)
+			}
+		}
+		if n := hits.Load(); n != 2 {
+			t.Errorf("hits = %d, want 2", n)
+		}
+	})
+}
+
+func TestParseCacheTTL(t *testing.T) {
+	got, err := parseCacheTTL("package=6h, score=1h,github=0s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]time.Duration{"package": 6 * time.Hour, "score": time.Hour, "github": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, in := range []string{"package", "package=abc", "=1h", "score=-1h"} {
+		if _, err := parseCacheTTL(in); err == nil {
+			t.Errorf("parseCacheTTL(%q): expected error", in)
+		}
+	}
+}
+
+func TestFormatGithubInfoEnterprise(t *testing.T) {
+	user, repo, ref, path := formatGithubInfo("https://ghe.example.com/org/repo/tree/main/packages/foo", "https://ghe.example.com")
+	if user != "org" || repo != "repo" || ref != "main" || path != "packages/foo" {
+		t.Errorf("got (%q, %q, %q, %q)", user, repo, ref, path)
+	}
+	if user, _, _, _ := formatGithubInfo("https://github.com/org/repo", "https://ghe.example.com"); user != "" {
+		t.Errorf("github.com link should not match an enterprise host, got user %q", user)
+	}
+}
+
+func TestNewEndpoints(t *testing.T) {
+	t.Setenv("PUB_HOSTED_URL", "")
+	t.Setenv("GITHUB_API_URL", "")
+	t.Setenv("GITHUB_SERVER_URL", "")
+
+	if got := newEndpoints("", "", ""); got != (Endpoints{PubURL: defaultPubURL, GithubAPIURL: defaultGithubAPIURL, GithubURL: defaultGithubURL}) {
+		t.Errorf("defaults: got %+v", got)
+	}
+
+	t.Setenv("PUB_HOSTED_URL", "https://pub.flutter-io.cn/")
+	if got := newEndpoints("", "", ""); got.PubURL != "https://pub.flutter-io.cn" {
+		t.Errorf("PUB_HOSTED_URL: got %q", got.PubURL)
+	}
+	if got := newEndpoints("http://127.0.0.1:8080", "", ""); got.PubURL != "http://127.0.0.1:8080" {
+		t.Errorf("flag should override PUB_HOSTED_URL: got %q", got.PubURL)
+	}
+
+	got := newEndpoints("", "https://ghe.example.com/api/v3", "")
+	if got.GithubAPIURL != "https://ghe.example.com/api/v3" || got.GithubURL != "https://ghe.example.com" {
+		t.Errorf("enterprise: got %+v", got)
+	}
+
+	t.Setenv("GITHUB_API_URL", "https://ghe.example.com/api/v3")
+	t.Setenv("GITHUB_SERVER_URL", "https://ghe.example.com")
+	got = newEndpoints("", "", "")
+	if got.GithubAPIURL != "https://ghe.example.com/api/v3" || got.GithubURL != "https://ghe.example.com" {
+		t.Errorf("enterprise from env: got %+v", got)
+	}
+}
+
+// 本地测试服务不限速的 HTTP Client
+func newTestHTTPClient() *HTTPClient {
+	client := newHTTPClient()
+	client.SetHostLimits(map[string]HostLimit{"127.0.0.1": {Concurrency: 8}})
+	return client
+}
+
+// 本地模拟 pub.dev 与 GitHub API
+func newFakeServer(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchPackageWithEndpoints(t *testing.T) {
+	github := newFakeServer(t, map[string]string{
+		"/api/v3/repos/org/repo":                         `{"stargazers_count":42,"license":{"name":"MIT License"},"archived":true,"pushed_at":"2026-01-01T00:00:00Z","default_branch":"main","topics":["flutter"]}`,
+		"/api/v3/repos/org/repo/contributors":            `[{"login":"alice","id":1,"type":"User"},{"login":"bot","id":2,"type":"Bot"}]`,
+		"/api/v3/repos/org/repo/commits/main/check-runs": `{"total_count":2,"check_runs":[{"status":"completed","conclusion":"success"},{"status":"in_progress","conclusion":null}]}`,
+		"/api/v3/repos/org/repo/tags":                    `[{"name":"v1.0.0"},{"name":"v0.9.0"}]`,
+		"/api/v3/repos/org/repo/releases/latest":         `{"tag_name":"v0.9.0"}`,
+		"/api/graphql":                                   `{"data":{"repository":{"issues":{"totalCount":5},"pullRequests":{"totalCount":3}}}}`,
+	})
+	pub := newFakeServer(t, map[string]string{
+		"/api/packages/foo":            `{"name":"foo","latest":{"pubspec":{"version":"1.0.0","repository":"` + github.URL + `/org/repo"},"published":"2026-01-01T00:00:00Z"}}`,
+		"/api/packages/foo/score":      `{"grantedPoints":150,"maxPoints":160,"likeCount":3,"tags":["platform:android"]}`,
+		"/api/packages/foo/advisories": `{"advisories":[{"id":"GHSA-1234","summary":"oops"}],"advisoriesUpdated":"2026-01-01T00:00:00Z"}`,
+	})
+	endpoints := newEndpoints(pub.URL, github.URL+"/api/v3", "")
+
+	info, err := fetchPackage(context.Background(), newTestHTTPClient(), endpoints, "token", PackageRef{Name: "foo", Source: PackageSource{URL: endpoints.PubURL}}, FetchOptions{Advisories: true, Activity: true, OpenCounts: true, Releases: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(info.Advisories, []string{"GHSA-1234"}) {
+		t.Errorf("advisories = %v", info.Advisories)
+	}
+	if info.Code != 1 || info.PubURL != pub.URL || info.Version != "1.0.0" {
+		t.Errorf("pub info = %+v", info)
+	}
+	if info.ScoreInfo.GrantedPoints != 150 || !reflect.DeepEqual(info.ScoreInfo.TagsPlatform, []string{"android"}) {
+		t.Errorf("score info = %+v", info.ScoreInfo)
+	}
+	if info.GithubUser != "org" || info.GithubRepo != "repo" || info.GithubBaseInfo.StargazersCount != 42 {
+		t.Errorf("github info = %+v", info)
+	}
+	if !info.GithubBaseInfo.Archived || info.GithubBaseInfo.DefaultBranch != "main" || !reflect.DeepEqual(info.GithubBaseInfo.Topics, []string{"flutter"}) || info.GithubCIStatus != ciPending {
+		t.Errorf("activity = %+v, ci %q", info.GithubBaseInfo, info.GithubCIStatus)
+	}
+	if !info.GithubReleaseChecked || info.GithubVersionTag != "v1.0.0" || info.GithubReleaseTag != "v0.9.0" {
+		t.Errorf("release = %q, tag %q", info.GithubReleaseTag, info.GithubVersionTag)
+	}
+	if len(info.GithubContributorsInfo) != 1 || info.GithubBaseInfo.ContributorsTotal != 2 {
+		t.Errorf("contributors = %+v, total %d", info.GithubContributorsInfo, info.GithubBaseInfo.ContributorsTotal)
+	}
+	if info.GithubOpenIssues != 5 || info.GithubOpenPullRequests != 3 {
+		t.Errorf("open issues %d, pull requests %d", info.GithubOpenIssues, info.GithubOpenPullRequests)
+	}
+
+	row := assembleMarkdownTableRow(info, TableOptions{GithubURL: endpoints.GithubURL})
+	if !strings.Contains(row.Name, "("+pub.URL+"/packages/foo)") {
+		t.Errorf("name link = %q", row.Name)
+	}
+	if !strings.Contains(row.GithubStars, "("+github.URL+"/org/repo)") {
+		t.Errorf("stars link = %q", row.GithubStars)
+	}
+
+	missing, err := fetchPackage(context.Background(), newTestHTTPClient(), endpoints, "token", PackageRef{Name: "missing", Source: PackageSource{URL: endpoints.PubURL}}, FetchOptions{})
+	if err != nil || missing.Code != 0 {
+		t.Errorf("missing package: info %+v, err %v", missing, err)
+	}
+}
+
+func TestGetPackageInfoSharesGithubRepo(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.RequestURI()]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/repos/org/mono":
+			w.Write([]byte(`{"stargazers_count":7,"default_branch":"main"}`))
+		case "/repos/org/mono/contributors":
+			w.Write([]byte(`[{"login":"alice","id":1,"type":"User"},{"login":"renovate-bot","id":2,"type":"User"}]`))
+		case "/repos/org/mono/tags":
+			w.Write([]byte(`[{"name":"foo-v1.0.0"},{"name":"bar-v2.0.0"}]`))
+		case "/search/issues":
+			if strings.Contains(r.URL.Query().Get("q"), `label:"p: foo"`) {
+				w.Write([]byte(`{"total_count":1}`))
+			} else {
+				w.Write([]byte(`{"total_count":4}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer github.Close()
+	routes := map[string]string{}
+	for name, version := range map[string]string{"foo": "1.0.0", "bar": "2.0.0"} {
+		routes["/api/packages/"+name] = `{"name":"` + name + `","latest":{"pubspec":{"version":"` + version + `","repository":"` + github.URL + `/org/mono/tree/main/packages/` + name + `"}}}`
+		routes["/api/packages/"+name+"/score"] = `{"grantedPoints":150,"maxPoints":160}`
+	}
+	pub := newFakeServer(t, routes)
+	endpoints := newEndpoints(pub.URL, github.URL, github.URL)
+	source := PackageSource{URL: pub.URL}
+
+	list, err := getPackageInfo(context.Background(), newTestHTTPClient(), endpoints, "", []PackageRef{{Name: "foo", Source: source}, {Name: "bar", Source: source}}, FetchOptions{OpenCounts: true, Releases: true, IssueLabels: []string{"p: {name}"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, count := range requests {
+		if count != 1 {
+			t.Errorf("%s requested %d times, want once", path, count)
+		}
+	}
+	// 未开启 activity 时不获取 CI 状态
+	if requests["/repos/org/mono/commits/main/check-runs?per_page=100"] != 0 {
+		t.Errorf("CI status fetched without activity")
+	}
+	if list[0].GithubOpenIssues != 4 || list[0].GithubLabelCounts["p: foo"] != (GithubOpenCounts{Issues: 1, PullRequests: 1}) || list[1].GithubLabelCounts["p: bar"] != (GithubOpenCounts{Issues: 4, PullRequests: 4}) {
+		t.Errorf("open counts = %d, %v, %v", list[0].GithubOpenIssues, list[0].GithubLabelCounts, list[1].GithubLabelCounts)
+	}
+	if list[0].GithubVersionTag != "foo-v1.0.0" || list[1].GithubVersionTag != "bar-v2.0.0" {
+		t.Errorf("version tags = %q, %q", list[0].GithubVersionTag, list[1].GithubVersionTag)
+	}
+	// 排除贡献者不影响同仓库的其他 package
+	excludeContributors(list[:1], []string{"*-bot"})
+	if len(list[0].GithubContributorsInfo) != 1 || len(list[1].GithubContributorsInfo) != 2 || list[1].GithubContributorsInfo[1].Login != "renovate-bot" {
+		t.Errorf("contributors = %+v, %+v", list[0].GithubContributorsInfo, list[1].GithubContributorsInfo)
+	}
+
+	// 不需要的数量与 tags / release 不请求
+	clear(requests)
+	list, err = getPackageInfo(context.Background(), newTestHTTPClient(), endpoints, "", []PackageRef{{Name: "foo", Source: source}}, FetchOptions{IssueLabels: []string{"p: {name}"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path := range requests {
+		if strings.HasPrefix(path, "/search/") || strings.HasPrefix(path, "/repos/org/mono/tags") || strings.HasPrefix(path, "/repos/org/mono/releases") {
+			t.Errorf("%s requested without OpenCounts / Releases", path)
+		}
+	}
+	if list[0].GithubReleaseChecked || list[0].GithubLabelCounts != nil {
+		t.Errorf("release checked %t, label counts %v", list[0].GithubReleaseChecked, list[0].GithubLabelCounts)
+	}
+}
+
+func TestTargetFetchOptions(t *testing.T) {
+	dir := t.TempDir()
+	stats := filepath.Join(dir, "stats.md")
+	os.WriteFile(stats, []byte("<!-- md:PubDashboard-total begin --><!-- md:PubDashboard-total end -->\n"), 0644)
+	readme := filepath.Join(dir, "README.md")
+	os.WriteFile(readme, []byte("<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->\n"), 0644)
+	weights, _ := parseHealthWeights(defaultHealthWeights)
+
+	for _, tt := range []struct {
+		name           string
+		target         Target
+		weights        map[string]int
+		strictVersions bool
+		want           FetchOptions
+	}{
+		{"summary markers only", Target{Filename: stats, Table: TableOptions{Render: renderNative, Activity: true, IssueLabel: "p: {name}"}}, weights, false, FetchOptions{}},
+		{"strict versions", Target{Filename: stats}, weights, true, FetchOptions{Releases: true}},
+		{"badge table", Target{Filename: readme, Table: TableOptions{Render: renderBadge, Activity: true, IssueLabel: "p: {name}"}}, weights, false, FetchOptions{Releases: true, Activity: true}},
+		{"native table", Target{Filename: readme, Table: TableOptions{Render: renderNative, IssueLabel: "p: {name}"}}, weights, false, FetchOptions{Releases: true, OpenCounts: true, IssueLabels: []string{"p: {name}"}}},
+		{"sort by issues", Target{Filename: stats, Table: TableOptions{SortField: "githubIssues"}}, weights, false, FetchOptions{OpenCounts: true}},
+		{"filter by pull requests", Target{Filename: stats, Filter: []FilterCondition{{Field: "githubPullRequests", Op: ">", Value: 1}}}, weights, false, FetchOptions{OpenCounts: true}},
+		{"attention marker", Target{Filename: stats, Markers: []string{markerAttention}}, weights, false, FetchOptions{OpenCounts: true}},
+		{"attention without issue weights", Target{Filename: stats, Markers: []string{markerAttention}}, map[string]int{healthPoints: 10}, false, FetchOptions{}},
+		{"releases marker", Target{Filename: readme, Markers: []string{markerReleases}}, weights, false, FetchOptions{Releases: true}},
+		{"missing file", Target{Filename: filepath.Join(dir, "missing.md"), Table: TableOptions{Render: renderSVG}}, weights, false, FetchOptions{Releases: true, OpenCounts: true}},
+	} {
+		if got := targetFetchOptions([]Target{tt.target}, tt.weights, tt.strictVersions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchHostedPackage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/private/api/packages/foo":
+			w.Write([]byte(`{"name":"foo","latest":{"pubspec":{"version":"2.0.0"},"published":"2026-01-01T00:00:00Z"}}`))
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer srv.Close()
+	endpoints := newEndpoints("", "", "")
+	source := PackageSource{URL: srv.URL + "/private", Token: "secret", Hosted: true}
+
+	info, err := fetchPackage(context.Background(), newTestHTTPClient(), endpoints, "", PackageRef{Name: "foo", Source: source}, FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Code != 1 || !info.Hosted || info.PubURL != source.URL || info.Version != "2.0.0" {
+		t.Errorf("hosted info = %+v", info)
+	}
+	if info.ScoreInfo.MaxPoints != 0 {
+		t.Errorf("score info = %+v, want empty", info.ScoreInfo)
+	}
+
+	row := assembleMarkdownTableRow(info, TableOptions{})
+	if !strings.Contains(row.Name, "("+source.URL+"/packages/foo)") {
+		t.Errorf("name link = %q", row.Name)
+	}
+	if strings.Contains(row.PubLikes+row.PubPoints+row.PubDownloadCount30Days, "img.shields.io") {
+		t.Errorf("hosted row uses shields badges: %+v", row)
+	}
+
+	_, err = fetchPackage(context.Background(), newTestHTTPClient(), endpoints, "", PackageRef{Name: "foo", Source: PackageSource{URL: source.URL, Hosted: true}}, FetchOptions{})
+	if err == nil {
+		t.Errorf("expected error without token")
+	}
+}
+
+func TestParseHostedPackageList(t *testing.T) {
+	got, err := parseHostedPackageList("https://pub.example.com/=aa, bb;\nhttps://pub2.example.com/x=cc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []PackageRef{
+		{Name: "aa", Source: PackageSource{URL: "https://pub.example.com", Hosted: true}},
+		{Name: "bb", Source: PackageSource{URL: "https://pub.example.com", Hosted: true}},
+		{Name: "cc", Source: PackageSource{URL: "https://pub2.example.com/x", Hosted: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHostedPackageList = %+v, want %+v", got, want)
+	}
+	for _, in := range []string{"aa,bb", "pub.example.com=aa", "https://pub.example.com=aa;https://pub2.example.com=aa"} {
+		if _, err := parseHostedPackageList(in); err == nil {
+			t.Errorf("parseHostedPackageList(%q) expected error", in)
+		}
+	}
+}
+
+func TestCheckDuplicatePackages(t *testing.T) {
+	pub := PackageSource{URL: defaultPubURL}
+	hosted := PackageSource{URL: "https://pub.example.com", Hosted: true}
+	if err := checkDuplicatePackages([]PackageRef{{Name: "foo", Source: pub}, {Name: "bar", Source: hosted}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := checkDuplicatePackages([]PackageRef{{Name: "foo", Source: pub}, {Name: "foo", Source: hosted}})
+	if err == nil || !strings.Contains(err.Error(), "https://pub.example.com") {
+		t.Errorf("got %v, want a duplicate error naming both sources", err)
+	}
+}
+
+func TestPubTokensTokenFor(t *testing.T) {
+	t.Setenv("PUB_TEST_TOKEN", "from-env")
+	filename := filepath.Join(t.TempDir(), "pub-tokens.json")
+	os.WriteFile(filename, []byte(`{"version":1,"hostedPubRepositories":[
+		{"url":"https://pub.example.com","token":"a"},
+		{"url":"https://pub.example.com/team/","env":"PUB_TEST_TOKEN"}
+	]}`), 0o600)
+	tokens, err := loadPubTokens(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := map[string]string{
+		"https://pub.example.com":       "a",
+		"https://pub.example.com/other": "a",
+		"https://pub.example.com/team":  "from-env",
+		"https://pub.example.com.evil":  "",
+		"https://pub.dev":               "",
+	}
+	for in, want := range tests {
+		if got := tokens.tokenFor(in); got != want {
+			t.Errorf("tokenFor(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := loadPubTokens(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing explicit file")
+	}
+}
+
+func TestGetPublisherPackages(t *testing.T) {
+	var mu sync.Mutex
+	requested := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Query().Get("q")+"/"+r.URL.Query().Get("page"))
+		mu.Unlock()
+		if r.URL.Path != "/api/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		publisher := strings.TrimPrefix(r.URL.Query().Get("q"), "publisher:")
+		switch publisher + "/" + r.URL.Query().Get("page") {
+		case "a.dev/1":
+			w.Write([]byte(`{"packages":[{"package":"a1"},{"package":"a2"}],"next":"page2"}`))
+		case "a.dev/2":
+			w.Write([]byte(`{"packages":[{"package":"a3"},{"package":"b1"}],"next":"page3"}`))
+		case "a.dev/3":
+			w.Write([]byte(`{"packages":[{"package":"a4"}]}`))
+		case "b.dev/1":
+			w.Write([]byte(`{"packages":[{"package":"b1"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := getPublisherPackages(context.Background(), newTestHTTPClient(), srv.URL, "a.dev,b.dev")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"a1", "a2", "a3", "b1", "a4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// 第 3 页没有 next，不再请求下一批（a.dev 第 1 页 + 第 2~4 页一批，b.dev 第 1 页）
+	if len(requested) != 5 {
+		t.Errorf("requested %v, want 5 pages", requested)
+	}
+
+	// next 异常时最多查询 maxPublisherPages 页
+	var pages atomic.Int32
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages.Add(1)
+		w.Write([]byte(`{"packages":[{"package":"p` + r.URL.Query().Get("page") + `"}],"next":"more"}`))
+	}))
+	defer loop.Close()
+	got, err = getPublisherPackages(context.Background(), newTestHTTPClient(), loop.URL, "loop.dev")
+	if err != nil || len(got) != maxPublisherPages || got[maxPublisherPages-1] != "p10" || pages.Load() != maxPublisherPages {
+		t.Errorf("got %v after %d pages, err %v", got, pages.Load(), err)
+	}
+}
+
+func TestGetGithubOpenCounts(t *testing.T) {
+	var graphQL atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/graphql":
+			var payload struct {
+				Query     string         `json:"query"`
+				Variables map[string]any `json:"variables"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			graphQL.Store(payload.Query)
+			switch {
+			case payload.Variables["name"] == "missing":
+				w.Write([]byte(`{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to a Repository"}]}`))
+			case payload.Variables["labels"] != nil:
+				w.Write([]byte(`{"data":{"repository":{"issues":{"totalCount":2},"pullRequests":{"totalCount":1}}}}`))
+			default:
+				w.Write([]byte(`{"data":{"repository":{"issues":{"totalCount":12},"pullRequests":{"totalCount":34}}}}`))
+			}
+		case "/search/issues":
+			q := r.URL.Query().Get("q")
+			switch {
+			case strings.Contains(q, "repo:org/missing"):
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			case strings.Contains(q, `label:"p: foo"`) && strings.Contains(q, "is:issue"):
+				w.Write([]byte(`{"total_count":2}`))
+			case strings.Contains(q, "is:issue"):
+				w.Write([]byte(`{"total_count":12}`))
+			default:
+				w.Write([]byte(`{"total_count":34}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		token string
+		repo  string
+		label string
+		want  GithubOpenCounts
+	}{
+		{"token", "repo", "", GithubOpenCounts{Issues: 12, PullRequests: 34}},
+		{"token", "repo", "p: foo", GithubOpenCounts{Issues: 2, PullRequests: 1}},
+		{"token", "missing", "", GithubOpenCounts{}},
+		{"", "repo", "", GithubOpenCounts{Issues: 12, PullRequests: 34}},
+		{"", "repo", "p: foo", GithubOpenCounts{Issues: 2, PullRequests: 34}},
+		{"", "missing", "", GithubOpenCounts{}},
+	} {
+		got, err := getGithubOpenCounts(context.Background(), newTestHTTPClient(), srv.URL, tt.token, "org", tt.repo, tt.label)
+		if err != nil || got != tt.want {
+			t.Errorf("token %q, %s, label %q: got %+v, %v, want %+v", tt.token, tt.repo, tt.label, got, err, tt.want)
+		}
+	}
+	if query, _ := graphQL.Load().(string); !strings.Contains(query, "issues(states: OPEN) { totalCount } pullRequests(states: OPEN) { totalCount }") {
+		t.Errorf("query = %q", query)
+	}
+
+	for apiURL, want := range map[string]string{
+		"https://api.github.com":         "https://api.github.com/graphql",
+		"https://ghe.example.com/api/v3": "https://ghe.example.com/api/graphql",
+	} {
+		if got := githubGraphQLURL(apiURL); got != want {
+			t.Errorf("githubGraphQLURL(%q) = %q, want %q", apiURL, got, want)
+		}
+	}
+
+	if got := linkLastPage(`<https://api.github.com/repos/org/repo/pulls?page=2>; rel="next"`); got != 0 {
+		t.Errorf("linkLastPage without last = %d", got)
+	}
+}
+
+func TestGetGithubContributorsInfoTotal(t *testing.T) {
+	var anon atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		anon.Store(r.URL.Query().Get("anon"))
+		switch r.URL.Path + "?per_page=" + r.URL.Query().Get("per_page") {
+		case "/repos/org/big/contributors?per_page=100":
+			w.Header().Set("Link", `<http://`+r.Host+`/repositories/1/contributors?per_page=100&page=2>; rel="next", <http://`+r.Host+`/repositories/1/contributors?per_page=100&page=3>; rel="last"`)
+			w.Write([]byte(`[{"login":"alice","id":1,"type":"User"},{"login":"ci","id":2,"type":"Bot"}]`))
+		case "/repos/org/big/contributors?per_page=1":
+			w.Header().Set("Link", `<http://`+r.Host+`/repositories/1/contributors?per_page=1&page=2>; rel="next", <http://`+r.Host+`/repositories/1/contributors?per_page=1&page=257>; rel="last"`)
+			w.Write([]byte(`[{"login":"alice","id":1,"type":"User"}]`))
+		case "/repos/org/small/contributors?per_page=100":
+			w.Write([]byte(`[{"login":"alice","id":1,"type":"User"},{"email":"a@example.com","type":"Anonymous"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	list, total, err := getGithubContributorsInfo(context.Background(), newTestHTTPClient(), srv.URL, "token", "org", "big", true)
+	if err != nil || len(list) != 1 || total != 257 {
+		t.Errorf("big: %d contributors, total %d, %v", len(list), total, err)
+	}
+	if anon.Load() != "1" {
+		t.Errorf("anon = %v, want 1", anon.Load())
+	}
+	list, total, err = getGithubContributorsInfo(context.Background(), newTestHTTPClient(), srv.URL, "token", "org", "small", false)
+	if err != nil || len(list) != 1 || total != 2 {
+		t.Errorf("small: %d contributors, total %d, %v", len(list), total, err)
+	}
+	if anon.Load() != "" {
+		t.Errorf("anon = %v, want empty", anon.Load())
+	}
+}
+
+func TestFilterPackageInfo(t *testing.T) {
+	list := []PackageInfo{
+		{Code: 1, Name: "a", GithubOpenPullRequests: 25, ScoreInfo: PackageScoreInfo{GrantedPoints: 160}},
+		{Code: 1, Name: "b", GithubOpenPullRequests: 3, ScoreInfo: PackageScoreInfo{GrantedPoints: 160}},
+		{Code: 1, Name: "c", GithubOpenPullRequests: 40, ScoreInfo: PackageScoreInfo{GrantedPoints: 100}},
+		{Code: 0, Name: "d"},
+	}
+	names := func(list []PackageInfo) []string {
+		out := []string{}
+		for _, p := range list {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	conditions, err := parseFilter("githubPullRequests>20, pubPoints >= 140")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := names(filterPackageInfo(list, conditions)); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("got %v", got)
+	}
+	conditions, _ = parseFilter("githubIssues=0")
+	if got := names(filterPackageInfo(list, conditions)); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("packages without info should be dropped: %v", got)
+	}
+	if got := filterPackageInfo(list, nil); len(got) != len(list) {
+		t.Errorf("empty filter dropped packages: %v", names(got))
+	}
+
+	for _, value := range []string{"githubPullRequests", "githubPullRequests>>1", "name>1", "githubStars>x"} {
+		if _, err := parseFilter(value); err == nil {
+			t.Errorf("parseFilter(%q): expected error", value)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := unifiedDiff("README.md", []byte("a\nb\n"), []byte("a\nb\n")); got != "" {
+		t.Errorf("unifiedDiff(same) = %q, want empty", got)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	new := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13"
+	want := "--- README.md\n+++ README.md\n" +
+		"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n\\ No newline at end of file\n"
+	if got := unifiedDiff("README.md", []byte(old), []byte(new)); got != want {
+		t.Errorf("unifiedDiff = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUpdateMarkdownMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "README.md")
+	content := "# Title\n<!-- md:PubDashboard-total begin -->1<!-- md:PubDashboard-total end -->\n" +
+		"<!-- md:PubDashboard begin -->\nold\n<!-- md:PubDashboard end -->\n"
+	os.WriteFile(filename, []byte(content), 0644)
+	blocks := MarkdownBlocks{Table: "| a |", Total: 2}
+
+	for _, mode := range []UpdateMode{UpdateModeDryRun, UpdateModeCheck} {
+		changed, err := updateMarkdown(filename, blocks, UpdateOptions{Mode: mode})
+		if err != nil || !changed {
+			t.Errorf("%s: changed %t, err %v", mode, changed, err)
+		}
+		if data, _ := os.ReadFile(filename); string(data) != content {
+			t.Errorf("%s: file was written", mode)
+		}
+	}
+
+	changed, err := updateMarkdown(filename, blocks, UpdateOptions{Mode: UpdateModeWrite})
+	if err != nil || !changed {
+		t.Errorf("write: changed %t, err %v", changed, err)
+	}
+	changed, err = updateMarkdown(filename, blocks, UpdateOptions{Mode: UpdateModeCheck})
+	if err != nil || changed {
+		t.Errorf("check after write: changed %t, err %v", changed, err)
+	}
+
+	// `$` 不应被当作替换模板展开
+	md, _ := replaceMarkdownBlocks([]byte(content), MarkdownBlocks{Table: "| $1 |"}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !strings.Contains(string(md), "| $1 |") || !strings.Contains(string(md), "Updated on 2026-01-01T00:00:00Z") {
+		t.Errorf("replaceMarkdownBlocks = %q", md)
+	}
+}
+
+func TestReplaceMarkdownTableTimestampOnly(t *testing.T) {
+	content := []byte("<!-- md:PubDashboard begin -->\nold\n<!-- md:PubDashboard end -->\n")
+	first, _ := replaceMarkdownBlocks(content, MarkdownBlocks{Table: "| a |"}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// 仅更新时间变化：保留原内容
+	second, _ := replaceMarkdownBlocks(first, MarkdownBlocks{Table: "| a |"}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if !bytes.Equal(first, second) {
+		t.Errorf("timestamp only change rewrote the block:\n%s", second)
+	}
+
+	// 内容变化：更新时间一并更新
+	third, _ := replaceMarkdownBlocks(first, MarkdownBlocks{Table: "| b |"}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if !strings.Contains(string(third), "| b |") || !strings.Contains(string(third), "Updated on 2026-01-02T00:00:00Z") {
+		t.Errorf("replaceMarkdownBlocks = %q", third)
+	}
+}
+
+func TestWriteChangedOutput(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	if err := writeChangedOutput(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeChangedOutput(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(outputFile); string(data) != "changed=false\nchanged=true\n" {
+		t.Errorf("GITHUB_OUTPUT = %q", data)
+	}
+}
+
+func TestUpdateMarkdownPreserveFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "README.md")
+	content := "\ufeff# Title\r\n<!-- md:PubDashboard-total begin -->1<!-- md:PubDashboard-total end -->\r\n" +
+		"<!-- md:PubDashboard begin -->\r\nold\r\n<!-- md:PubDashboard end -->\r\n"
+	os.WriteFile(filename, []byte(content), 0600)
+	link := filepath.Join(dir, "link.md")
+	if err := os.Symlink(filename, link); err != nil {
+		t.Skipf("symlink: %v", err)
+	}
+
+	if _, err := updateMarkdown(link, MarkdownBlocks{Table: "| a |\n| b |", Total: 2}, UpdateOptions{Mode: UpdateModeWrite}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filename)
+	if !strings.HasPrefix(string(data), "\ufeff# Title\r\n") {
+		t.Errorf("BOM lost: %q", data)
+	}
+	if n := strings.Count(string(data), "\n"); n != strings.Count(string(data), "\r\n") {
+		t.Errorf("mixed line endings: %q", data)
+	}
+	if !strings.Contains(string(data), "| a |\r\n| b |") || !strings.Contains(string(data), "-->2<!--") {
+		t.Errorf("blocks not replaced: %q", data)
+	}
+	if stat, _ := os.Stat(filename); stat.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", stat.Mode().Perm())
+	}
+	if stat, _ := os.Lstat(link); stat.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink replaced by a regular file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("temp files left: %v", entries)
+	}
+}
+
+func TestScanMarkdownMarkers(t *testing.T) {
+	const table = "<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->\n"
+	tests := []struct {
+		name   string
+		in     string
+		blocks int
+		want   []MarkerDiagnostic
+	}{
+		{"valid", "# T\n" + table + "total: <!-- md:PubDashboard-total begin -->1<!-- md:PubDashboard-total end -->\n", 2, []MarkerDiagnostic{}},
+		{"fenced code", table + "```\n<!-- md:PubDashboard begin -->\n````\n~~~md\n<!-- md:Oops -->\n~~~\n", 1, []MarkerDiagnostic{}},
+		{"inline code", table + "use `<!-- md:PubDashboard-total begin -->`\n", 1, []MarkerDiagnostic{}},
+		{"missing table", "# T\n", 0, []MarkerDiagnostic{}},
+		{"misspelled", "<!-- md:PubDashbaord begin -->\n" + table, 1, []MarkerDiagnostic{
+			{Line: 1, Message: `unknown marker "<!-- md:PubDashbaord begin -->"`, Warning: true},
+		}},
+		{"malformed", "<!-- md:PubDashboard start -->\n" + table, 1, []MarkerDiagnostic{
+			{Line: 1, Message: `malformed marker "<!-- md:PubDashboard start -->", want <!-- md:NAME begin --> or <!-- md:NAME end -->`, Warning: true},
+		}},
+		{"missing end", "\n<!-- md:PubDashboard begin -->\n", 0, []MarkerDiagnostic{
+			{Line: 2, Message: `"PubDashboard" begin marker without end`},
+		}},
+		{"missing begin", table + "<!-- md:PubDashboard-total end -->\n", 1, []MarkerDiagnostic{
+			{Line: 2, Message: `"<!-- md:PubDashboard-total end -->" without begin marker`},
+		}},
+		{"nested", "<!-- md:PubDashboard begin -->\n<!-- md:PubDashboard-total begin -->\n<!-- md:PubDashboard end -->\n", 1, []MarkerDiagnostic{
+			{Line: 2, Message: `nested marker "<!-- md:PubDashboard-total begin -->" inside "PubDashboard" (line 1)`},
+		}},
+		{"mismatched", "<!-- md:PubDashboard-total begin -->\n<!-- md:PubDashboard end -->\n", 0, []MarkerDiagnostic{
+			{Line: 2, Message: `"<!-- md:PubDashboard end -->" does not match begin marker "PubDashboard-total" (line 1)`},
+			{Line: 1, Message: `"PubDashboard-total" begin marker without end`},
+		}},
+		{"duplicate", table + "\n" + table, 2, []MarkerDiagnostic{
+			{Line: 3, Message: `duplicate marker "PubDashboard" (first at line 1)`, Warning: true},
+		}},
+	}
+	for _, tt := range tests {
+		blocks, diagnostics := scanMarkdownMarkers([]byte(tt.in))
+		if len(blocks) != tt.blocks {
+			t.Errorf("%s: blocks = %+v, want %d", tt.name, blocks, tt.blocks)
+		}
+		if !reflect.DeepEqual(diagnostics, tt.want) {
+			t.Errorf("%s: diagnostics = %+v, want %+v", tt.name, diagnostics, tt.want)
+		}
+	}
+}
+
+func TestUpdateMarkdownStrict(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "README.md")
+	content := "<!-- md:PubDashboard-total begin -->1<!-- md:PubDashboard-total end -->\n"
+	os.WriteFile(filename, []byte(content), 0644)
+
+	if _, err := updateMarkdown(filename, MarkdownBlocks{Total: 2}, UpdateOptions{Mode: UpdateModeCheck, Strict: true}); err == nil {
+		t.Errorf("strict: expected error for missing table marker")
+	}
+	if _, err := updateMarkdown(filename, MarkdownBlocks{Total: 2, Markers: []string{markerTotal}}, UpdateOptions{Mode: UpdateModeCheck, Strict: true}); err != nil {
+		t.Errorf("strict with markers: unexpected error: %v", err)
+	}
+	if _, err := updateMarkdown(filename, MarkdownBlocks{Total: 2}, UpdateOptions{Mode: UpdateModeWrite}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	os.WriteFile(filename, []byte("<!-- md:PubDashboard begin -->\n"), 0644)
+	if _, err := updateMarkdown(filename, MarkdownBlocks{}, UpdateOptions{Mode: UpdateModeWrite}); err == nil {
+		t.Errorf("expected error for missing end marker")
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "<!-- md:PubDashboard begin -->\n" {
+		t.Errorf("file written despite invalid markers: %q", data)
+	}
+}
+
+func TestComputePackageStats(t *testing.T) {
+	contributors := []GithubContributorsInfo{{Login: "a", Id: 1, Type: "User", Contributions: 10}, {Login: "b", Id: 2, Type: "User", Contributions: 5}}
+	list := []PackageInfo{
+		{Code: 1, Name: "a", GithubUser: "org", GithubRepo: "mono", GithubContributorsInfo: contributors,
+			GithubBaseInfo: GithubBaseInfo{StargazersCount: 100},
+			ScoreInfo:      PackageScoreInfo{DownloadCount30Days: 1500, LikeCount: 10, GrantedPoints: 160, MaxPoints: 160, TagsPlatform: []string{"android", "ios"}}},
+		{Code: 1, Name: "b", GithubUser: "Org", GithubRepo: "Mono", GithubContributorsInfo: contributors,
+			GithubBaseInfo: GithubBaseInfo{StargazersCount: 100},
+			ScoreInfo:      PackageScoreInfo{DownloadCount30Days: 500, LikeCount: 5, GrantedPoints: 135, MaxPoints: 160, TagsPlatform: []string{"android"}}},
+		{Code: 1, Name: "c", GithubUser: "org", GithubRepo: "other",
+			GithubContributorsInfo: []GithubContributorsInfo{{Login: "b", Id: 2, Type: "User", Contributions: 3}, {Login: "c", Id: 3, Type: "User", Contributions: 20}},
+			GithubBaseInfo:         GithubBaseInfo{StargazersCount: 7},
+			ScoreInfo:              PackageScoreInfo{LikeCount: 1, TagsPlatform: []string{"web"}}},
+		{Code: 0, Name: "missing", ScoreInfo: PackageScoreInfo{DownloadCount30Days: 1000}},
+	}
+	got := computePackageStats(list)
+	leaderboard := []string{}
+	for _, rank := range got.Leaderboard {
+		leaderboard = append(leaderboard, rank.Login+":"+strconv.Itoa(rank.Packages)+":"+strconv.Itoa(rank.Contributions))
+	}
+	// 同一仓库的提交数只计一次，package 数按 package 计
+	if want := []string{"b:3:8", "a:2:10", "c:1:20"}; !reflect.DeepEqual(leaderboard, want) {
+		t.Errorf("leaderboard = %v, want %v", leaderboard, want)
+	}
+	got.Leaderboard = nil
+	want := PackageStats{
+		Downloads:    2000,
+		Likes:        16,
+		Stars:        107,
+		Points:       148,
+		Platforms:    map[string]int{"android": 2, "ios": 1, "web": 1},
+		Contributors: 3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computePackageStats = %+v, want %+v", got, want)
+	}
+
+	md, _ := replaceMarkdownBlocks([]byte("<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->\n"+
+		"Downloaded <!-- md:PubDashboard-downloads begin --><!-- md:PubDashboard-downloads end --> times, "+
+		"<!-- md:PubDashboard-platforms begin -->old<!-- md:PubDashboard-platforms end -->."),
+		MarkdownBlocks{Stats: got}, time.Now())
+	if !strings.Contains(string(md), "-->2k<!--") || !strings.Contains(string(md), "-->android 2, ios 1, web 1<!--") {
+		t.Errorf("replaceMarkdownBlocks = %q", md)
+	}
+}
+
+func TestFormatLeaderboard(t *testing.T) {
+	ranks := []ContributorRank{
+		{GithubContributorsInfo: GithubContributorsInfo{Login: "b", Id: 2, HtmlUrl: "https://github.com/b"}, Packages: 3, Contributions: 8},
+		{GithubContributorsInfo: GithubContributorsInfo{Login: "a", Id: 1, HtmlUrl: "https://github.com/a"}, Packages: 2, Contributions: 10},
+	}
+	got := formatLeaderboard(ranks, LeaderboardOptions{}, getLocale("en"))
+	want := " \n" +
+		"| # | Contributor | Packages | Commits | \n" +
+		"|:-:|-------------|:-:|:-:| \n" +
+		`| 1 | <img width="24px" src="` + getGithubAvatarUrl(2) + `" /> [@b](https://github.com/b) | 3 | 8 | ` + "\n" +
+		`| 2 | <img width="24px" src="` + getGithubAvatarUrl(1) + `" /> [@a](https://github.com/a) | 2 | 10 | ` + "\n"
+	if got != want {
+		t.Errorf("table = %q, want %q", got, want)
+	}
+	got = formatLeaderboard(ranks, LeaderboardOptions{Layout: leaderboardAvatars, Count: 1}, getLocale("zh-CN"))
+	if want := `<a href="https://github.com/b"><img width="48px" src="` + getGithubAvatarUrl(2) + `" title="@b: 3 个 package" /></a>`; got != want {
+		t.Errorf("avatars = %q, want %q", got, want)
+	}
+	if got := formatLeaderboard(nil, LeaderboardOptions{}, getLocale("en")); got != "-" {
+		t.Errorf("empty = %q", got)
+	}
+
+	md, _ := replaceMarkdownBlocks([]byte("<!-- md:PubDashboard-leaderboard begin --><!-- md:PubDashboard-leaderboard end -->\n"+
+		"<!-- md:PubDashboard-contributors begin --><!-- md:PubDashboard-contributors end -->"),
+		MarkdownBlocks{Stats: PackageStats{Contributors: 2, Leaderboard: ranks}, Markers: []string{markerContributors, markerLeaderboard}}, time.Now())
+	if !strings.Contains(string(md), "| 1 | <img") || !strings.Contains(string(md), "contributors begin -->2<!--") {
+		t.Errorf("replaceMarkdownBlocks = %q", md)
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	defaults := Target{Filename: "README.md", SortMode: "asc", Table: TableOptions{SortField: "name", GithubURL: defaultGithubURL}}
+
+	got, err := parseTargets("", defaults, "repo")
+	if err != nil || len(got) != 1 || got[0].Filename != filepath.Join("repo", "README.md") {
+		t.Errorf("parseTargets(empty) = %+v, %v", got, err)
+	}
+
+	got, err = parseTargets(`
+# comment
+README.md
+README_CN.md sortField=pubDownloads sortMode=desc groupByRepo=true issueLabel="p: {name}" locale=zh-CN
+docs/stats.md markers=total,PubDashboard-downloads,attention filter=githubPullRequests>20 health=true attentionCount=3 attentionThreshold=0
+/abs/other.md
+`, defaults, "repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	threshold := 0
+	want := []Target{
+		{Filename: filepath.Join("repo", "README.md"), SortMode: "asc", Table: defaults.Table},
+		{Filename: filepath.Join("repo", "README_CN.md"), SortMode: "desc", Table: TableOptions{SortField: "pubDownloads", GroupByRepo: true, IssueLabel: "p: {name}", GithubURL: defaultGithubURL, Locale: "zh-CN"}},
+		{Filename: filepath.Join("repo", "docs/stats.md"), SortMode: "asc", Table: TableOptions{SortField: "name", GithubURL: defaultGithubURL, Health: true}, Markers: []string{markerTotal, markerDownloads, markerAttention},
+			Filter: []FilterCondition{{Field: "githubPullRequests", Op: ">", Value: 20}}, Attention: AttentionOptions{Count: 3, Threshold: &threshold}},
+		{Filename: "/abs/other.md", SortMode: "asc", Table: defaults.Table},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTargets = %+v, want %+v", got, want)
+	}
+
+	for _, in := range []string{"a.md sortField", "a.md color=red", "a.md markers=oops", "a.md groupByRepo=maybe", "a.md locale=fr", "a.md filter=oops", "a.md activity=maybe", "a.md contributorsLayout=grid", "a.md contributorsCount=0", "a.md leaderboardLayout=grid", "a.md leaderboardCount=x", "a.md health=maybe", "a.md attentionThreshold=-1", `a.md issueLabel="p`} {
+		if _, err := parseTargets(in, defaults, ""); err == nil {
+			t.Errorf("parseTargets(%q) expected error", in)
+		}
+	}
+}
+
+func TestReplaceMarkdownBlocksMarkers(t *testing.T) {
+	content := "<!-- md:PubDashboard begin -->old<!-- md:PubDashboard end -->\n" +
+		"<!-- md:PubDashboard-total begin -->1<!-- md:PubDashboard-total end -->\n"
+	md, _ := replaceMarkdownBlocks([]byte(content), MarkdownBlocks{Table: "| a |", Total: 2, Markers: []string{markerTotal}}, time.Now())
+	if !strings.Contains(string(md), "-->old<!--") || !strings.Contains(string(md), "-->2<!--") {
+		t.Errorf("replaceMarkdownBlocks = %q", md)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	zh := getLocale("zh-CN")
+	tests := map[int]string{999: "999", 15000: "1.5万", 123456789: "1.23亿"}
+	for in, want := range tests {
+		if got := zh.formatCount(in); got != want {
+			t.Errorf("zh-CN formatCount(%d) = %q, want %q", in, got, want)
+		}
+	}
+	if got := zh.formatPublished("2024-05-06T07:08:09.123Z"); got != "2024-05-06 07:08" {
+		t.Errorf("zh-CN formatPublished = %q", got)
+	}
+	if got := getLocale("").formatPublished("2024-05-06T07:08:09.123Z"); got != "2024-05-06T07:08:09.123Z" {
+		t.Errorf("en formatPublished = %q", got)
+	}
+
+	info := PackageInfo{Code: 1, Name: "foo", Version: "1.0.0", Published: "2024-05-06T07:08:09Z", GithubUser: "org", GithubRepo: "repo",
+		ScoreInfo: PackageScoreInfo{DownloadCount30Days: 15000}}
+	table := assembleMarkdownTable([]PackageInfo{info}, TableOptions{SortField: "pubDownloads", Locale: "zh-CN"})
+	for _, want := range []string{"排序：下载量 | 共 1 个", "<sub>贡献者</sub>", "<strong>平台:</strong> -", "<strong>发布时间:</strong> 2024-05-06 07:08", "<strong>许可证:</strong> -", "/badge/1.5%E4%B8%87%2F%E6%9C%88-4AC51C"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("zh-CN table missing %q:\n%s", want, table)
+		}
+	}
+
+	// 本地化页脚：仅更新时间变化时保留原内容
+	content := []byte("<!-- md:PubDashboard begin --><!-- md:PubDashboard end -->")
+	first, _ := replaceMarkdownBlocks(content, MarkdownBlocks{Table: table, Locale: "zh-CN"}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !strings.Contains(string(first), "更新于 2026-01-01 00:00:00 (UTC+00:00)。") {
+		t.Errorf("zh-CN footer: %q", first)
+	}
+	second, _ := replaceMarkdownBlocks(first, MarkdownBlocks{Table: table, Locale: "zh-CN"}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if !bytes.Equal(first, second) {
+		t.Errorf("timestamp only change rewrote the zh-CN block")
+	}
+}
+
+func TestAssembleMarkdownTableRowNative(t *testing.T) {
+	info := PackageInfo{Code: 1, Name: "foo", Version: "1.0.0", GithubUser: "org", GithubRepo: "repo",
+		GithubBaseInfo: GithubBaseInfo{StargazersCount: 1234, OpenIssuesCount: 10}, GithubOpenIssues: 7, GithubOpenPullRequests: 3,
+		ScoreInfo: PackageScoreInfo{DownloadCount30Days: 15000, LikeCount: 42, GrantedPoints: 150, MaxPoints: 160}}
+	row := assembleMarkdownTableRow(info, TableOptions{Render: renderNative})
+	want := MarkdownTable{
+		GithubStars:            "[⭐ 1.23k](https://github.com/org/repo)",
+		PubLikes:               "[👍 42](https://pub.dev/packages/foo)",
+		PubPoints:              "[150/160](https://pub.dev/packages/foo/score)",
+		PubDownloadCount30Days: "[15k/month](https://pub.dev/packages/foo)",
+		Issues:                 "[🐞 7](https://github.com/org/repo/issues)",
+		PullRequests:           "[🔀 3](https://github.com/org/repo/pulls)",
+	}
+	got := MarkdownTable{GithubStars: row.GithubStars, PubLikes: row.PubLikes, PubPoints: row.PubPoints,
+		PubDownloadCount30Days: row.PubDownloadCount30Days, Issues: row.Issues, PullRequests: row.PullRequests}
+	if got != want {
+		t.Errorf("native row = %+v, want %+v", got, want)
+	}
+
+	// 无评分数据
+	info.ScoreInfo = PackageScoreInfo{}
+	row = assembleMarkdownTableRow(info, TableOptions{Render: renderNative})
+	if row.PubLikes != "-" || row.PubPoints != "-" || row.PubDownloadCount30Days != "-" {
+		t.Errorf("native row without score = %+v", row)
+	}
+	if table := assembleMarkdownTable([]PackageInfo{info}, TableOptions{Render: renderNative}); strings.Contains(table, "img.shields.io") {
+		t.Errorf("native table uses shields badges:\n%s", table)
+	}
+}
+
+func TestGetGithubCIStatus(t *testing.T) {
+	github := newFakeServer(t, map[string]string{
+		"/repos/org/ok/commits/main/check-runs":     `{"check_runs":[{"status":"completed","conclusion":"success"},{"status":"completed","conclusion":"skipped"}]}`,
+		"/repos/org/failed/commits/main/check-runs": `{"check_runs":[{"status":"in_progress"},{"status":"completed","conclusion":"timed_out"}]}`,
+		"/repos/org/empty/commits/main/check-runs":  `{"check_runs":[]}`,
+	})
+	for repo, want := range map[string]string{"ok": ciSuccess, "failed": ciFailure, "empty": "", "missing": ""} {
+		got, err := getGithubCIStatus(context.Background(), newTestHTTPClient(), github.URL, "token", "org", repo, "main")
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", repo, got, err, want)
+		}
+	}
+}
+
+func TestFormatActivity(t *testing.T) {
+	info := PackageInfo{Code: 1, Name: "foo", GithubUser: "org", GithubRepo: "repo", GithubCIStatus: ciFailure,
+		GithubBaseInfo: GithubBaseInfo{Archived: true, PushedAt: "2026-01-01T08:00:00Z", DefaultBranch: "main"}}
+	now := time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC)
+
+	row := assembleMarkdownTableRow(info, TableOptions{Activity: true, Now: now})
+	want := "🗄️ <strong>Archived</strong> · <strong>Last push:</strong> 11 days ago · <strong>CI:</strong> [❌](https://github.com/org/repo/commits/main)"
+	if row.Activity != want {
+		t.Errorf("activity = %q, want %q", row.Activity, want)
+	}
+	if !strings.Contains(formatMarkdownTableRow(row, TableOptions{}), "<sub>"+want+"</sub> | ") {
+		t.Errorf("row = %q", formatMarkdownTableRow(row, TableOptions{}))
+	}
+	if row := assembleMarkdownTableRow(info, TableOptions{}); row.Activity != "" || strings.Contains(formatMarkdownTableRow(row, TableOptions{}), "Last push") {
+		t.Errorf("activity shown without the option: %q", row.Activity)
+	}
+
+	info.GithubBaseInfo.Archived, info.GithubCIStatus = false, ""
+	info.GithubBaseInfo.PushedAt = "2026-01-12T08:00:00Z"
+	if row := assembleMarkdownTableRow(info, TableOptions{Activity: true, Now: now, Locale: "zh-CN"}); row.Activity != "<strong>最近推送:</strong> 今天" {
+		t.Errorf("zh-CN activity = %q", row.Activity)
+	}
+}
+
+func TestMatchVersionTag(t *testing.T) {
+	for _, tt := range []struct {
+		tag  string
+		want bool
+	}{
+		{"1.2.3", true},
+		{"v1.2.3", true},
+		{"foo-v1.2.3", true},
+		{"foo_v1.2.3", true},
+		{"foo@1.2.3", true},
+		{"foo/v1.2.3", true},
+		{"v1.2.30", false},
+		{"bar-v1.2.3", false},
+		{"release-1.2.3", false},
+	} {
+		if got := matchVersionTag(tt.tag, "foo", "1.2.3"); got != tt.want {
+			t.Errorf("matchVersionTag(%q) = %t, want %t", tt.tag, got, tt.want)
+		}
+	}
+	candidates := versionTagCandidates("foo", "1.2.3")
+	if len(candidates) != 10 {
+		t.Errorf("candidates = %v", candidates)
+	}
+	for _, tag := range candidates {
+		if !matchVersionTag(tag, "foo", "1.2.3") {
+			t.Errorf("candidate %q does not match", tag)
+		}
+	}
+}
+
+func TestFindGithubVersionTag(t *testing.T) {
+	var refs []string
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/org/mono/tags":
+			// 首页（按名称排序）不含 foo 的 tag
+			w.Header().Set("Link", `<http://`+r.Host+`/repositories/1/tags?per_page=100&page=2>; rel="next", <http://`+r.Host+`/repositories/1/tags?per_page=100&page=9>; rel="last"`)
+			w.Write([]byte(`[{"name":"zed-v9.0.0"},{"name":"bar-v2.0.0"}]`))
+		case strings.HasPrefix(r.URL.Path, "/repos/org/mono/git/ref/tags/"):
+			refs = append(refs, strings.TrimPrefix(r.URL.Path, "/repos/org/mono/git/ref/tags/"))
+			if r.URL.Path == "/repos/org/mono/git/ref/tags/foo/v1.0.0" {
+				w.Write([]byte(`{"ref":"refs/tags/foo/v1.0.0"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer github.Close()
+	routes := map[string]string{}
+	for name, version := range map[string]string{"foo": "1.0.0", "bar": "2.0.0"} {
+		routes["/api/packages/"+name] = `{"name":"` + name + `","latest":{"pubspec":{"version":"` + version + `","repository":"` + github.URL + `/org/mono/tree/main/packages/` + name + `"}}}`
+	}
+	pub := newFakeServer(t, routes)
+	endpoints := newEndpoints(pub.URL, github.URL, github.URL)
+	source := PackageSource{URL: pub.URL}
+
+	list, err := getPackageInfo(context.Background(), newTestHTTPClient(), endpoints, "", []PackageRef{{Name: "foo", Source: source}, {Name: "bar", Source: source}}, FetchOptions{Releases: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list[0].GithubVersionTag != "foo/v1.0.0" || list[1].GithubVersionTag != "bar-v2.0.0" {
+		t.Errorf("version tags = %q, %q", list[0].GithubVersionTag, list[1].GithubVersionTag)
+	}
+	// bar 在首页中已匹配，不再逐个查询
+	if want := []string{"1.0.0", "v1.0.0", "foo-1.0.0", "foo-v1.0.0", "foo_1.0.0", "foo_v1.0.0", "foo@1.0.0", "foo@v1.0.0", "foo/1.0.0", "foo/v1.0.0"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs = %v, want %v", refs, want)
+	}
+}
+
+func TestReleaseMismatches(t *testing.T) {
+	list := []PackageInfo{
+		{Code: 1, Name: "foo", Version: "1.2.3", GithubReleaseChecked: true, GithubVersionTag: "v1.2.3", GithubReleaseTag: "v1.2.3"},
+		{Code: 1, Name: "bar", Version: "2.0.0", GithubReleaseChecked: true, GithubReleaseTag: "bar-v1.9.0"},
+		{Code: 1, Name: "baz", Version: "0.1.0", GithubReleaseChecked: true, GithubVersionTag: "baz-v0.1.0"},
+		{Code: 1, Name: "qux", Version: "1.0.0"},
+	}
+	row := assembleMarkdownTableRow(list[1], TableOptions{})
+	if row.Release != "" {
+		t.Errorf("release shown without a Github repo: %q", row.Release)
+	}
+	list[1].GithubUser, list[1].GithubRepo = "org", "bar"
+	row = assembleMarkdownTableRow(list[1], TableOptions{})
+	if want := "🏷️ no git tag · latest release: bar-v1.9.0"; row.Release != want || !strings.Contains(formatMarkdownTableRow(row, TableOptions{}), "<sub>"+want+"</sub>") {
+		t.Errorf("release = %q, want %q", row.Release, want)
+	}
+
+	stats := computePackageStats(list)
+	if got := renderMarkdownBlock(markerReleases, MarkdownBlocks{Stats: stats}, time.Now()); got != "bar v2.0.0 (no git tag, latest release: bar-v1.9.0)" {
+		t.Errorf("releases marker = %q", got)
+	}
+	if got := renderMarkdownBlock(markerReleases, MarkdownBlocks{Stats: computePackageStats(list[2:])}, time.Now()); got != "-" {
+		t.Errorf("releases marker without mismatches = %q", got)
+	}
+	if got := untaggedPackages(list); len(got) != 1 || got[0].Name != "bar" {
+		t.Errorf("untaggedPackages = %+v", got)
+	}
+}
+
+func TestFormatContributors(t *testing.T) {
+	list := []GithubContributorsInfo{}
+	for i, login := range []string{"a", "b", "c", "d", "e"} {
+		list = append(list, GithubContributorsInfo{Login: login, Id: i + 1, HtmlUrl: "https://github.com/" + login})
+	}
+	avatar := func(i int, width string) string {
+		return `<a href="https://github.com/` + list[i].Login + `"><img width="` + width + `px" src="` + getGithubAvatarUrl(list[i].Id) + `" /></a>`
+	}
+	total := `<tr align="center"><td colspan="2"><a href="link">Total: 5</a></td></tr></table>`
+
+	if got, want := formatContributors(list[:2], 5, "link", TableOptions{}), `<table align="center" border="0"><tr align="center"><td>`+avatar(0, "30")+`</td><td>`+avatar(1, "30")+`</td></tr>`+total; got != want {
+		t.Errorf("stacked 2 = %q, want %q", got, want)
+	}
+	got := formatContributors(list, 5, "link", TableOptions{ContributorsCount: 5, ContributorsSize: 48})
+	want := `<table align="center" border="0"><tr align="center"><td colspan="2">` + avatar(0, "48") + `</td></tr>` +
+		`<tr align="center"><td>` + avatar(1, "40") + `</td><td>` + avatar(2, "40") + `</td></tr>` +
+		`<tr align="center"><td>` + avatar(3, "40") + `</td><td>` + avatar(4, "40") + `</td></tr>` + total
+	if got != want {
+		t.Errorf("stacked 5 = %q, want %q", got, want)
+	}
+	if got, want := formatContributors(list, 120, "link", TableOptions{ContributorsLayout: contributorsInline, ContributorsCount: 2, ContributorsSize: 20}), avatar(0, "20")+" "+avatar(1, "20")+` <br/> <a href="link">Total: 120</a>`; got != want {
+		t.Errorf("inline = %q, want %q", got, want)
+	}
+	if got, want := formatContributors(list, 5, "link", TableOptions{ContributorsLayout: contributorsNames, Locale: "zh-CN"}), `[@a](https://github.com/a), [@b](https://github.com/b), [@c](https://github.com/c) <br/> <a href="link">共: 5</a>`; got != want {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+
+	info := PackageInfo{Code: 1, Name: "foo", GithubUser: "org", GithubRepo: "repo", GithubContributorsInfo: list}
+	table := assembleMarkdownTable([]PackageInfo{info}, TableOptions{ContributorsLayout: contributorsNone})
+	if strings.Contains(table, "Contributors") || strings.Contains(table, "avatars") || strings.Contains(table, ":---") || !strings.HasSuffix(table, "/pulls) | \n") {
+		t.Errorf("table with contributors = none:\n%s", table)
+	}
+}
+
+func TestExcludeContributors(t *testing.T) {
+	list := []PackageInfo{{
+		GithubContributorsInfo: []GithubContributorsInfo{{Login: "alice"}, {Login: "Renovate-Bot"}, {Login: "release-bot"}, {Login: "bob"}},
+		GithubBaseInfo:         GithubBaseInfo{ContributorsTotal: 10},
+	}}
+	excludeContributors(list, []string{"renovate-bot", "release-*"})
+	logins := []string{}
+	for _, contributor := range list[0].GithubContributorsInfo {
+		logins = append(logins, contributor.Login)
+	}
+	if !reflect.DeepEqual(logins, []string{"alice", "bob"}) || list[0].GithubBaseInfo.ContributorsTotal != 8 {
+		t.Errorf("contributors = %v, total %d", logins, list[0].GithubBaseInfo.ContributorsTotal)
+	}
+}
+
+func TestSVGBadges(t *testing.T) {
+	info := PackageInfo{Code: 1, Name: "foo", GithubUser: "org", GithubRepo: "repo",
+		GithubBaseInfo: GithubBaseInfo{StargazersCount: 1234},
+		ScoreInfo:      PackageScoreInfo{DownloadCount30Days: 15000, LikeCount: 42, GrantedPoints: 150, MaxPoints: 160}}
+
+	row := assembleMarkdownTableRow(info, TableOptions{Render: renderSVG, BadgeURL: "../assets"})
+	if row.PubDownloadCount30Days != "[![Pub downloads](../assets/foo-downloads.svg)](https://pub.dev/packages/foo)" {
+		t.Errorf("downloads = %q", row.PubDownloadCount30Days)
+	}
+	if row.GithubStars != "[![GitHub stars](../assets/foo-stars.svg)](https://github.com/org/repo)" {
+		t.Errorf("stars = %q", row.GithubStars)
+	}
+	if row.PullRequests != "[![GitHub pull requests](../assets/foo-pulls.svg)](https://github.com/org/repo/pulls)" {
+		t.Errorf("pull requests = %q", row.PullRequests)
+	}
+
+	badges := collectBadges([]PackageInfo{info, {Code: 0, Name: "missing"}}, TableOptions{Locale: "zh-CN"})
+	names := slices.Sorted(maps.Keys(badges))
+	want := []string{"foo-downloads.zh-CN.svg", "foo-issues.zh-CN.svg", "foo-likes.zh-CN.svg", "foo-points.zh-CN.svg", "foo-pulls.zh-CN.svg", "foo-stars.zh-CN.svg"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("badges = %v, want %v", names, want)
+	}
+	if !bytes.Contains(badges["foo-downloads.zh-CN.svg"], []byte(">1.5万/月</text>")) || !bytes.Contains(badges["foo-downloads.zh-CN.svg"], []byte(downloadIcon)) {
+		t.Errorf("downloads badge = %s", badges["foo-downloads.zh-CN.svg"])
+	}
+	if !bytes.Equal(renderBadgeSVG(Badge{Label: "stars", Message: "1k", Color: "#000"}), renderBadgeSVG(Badge{Label: "stars", Message: "1k", Color: "#000"})) {
+		t.Errorf("renderBadgeSVG is not deterministic")
+	}
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	if changed, err := updateBadges(dir, badges, UpdateModeCheck); err != nil || !changed {
+		t.Errorf("check: changed %t, err %v", changed, err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("check created the assets dir")
+	}
+	if changed, err := updateBadges(dir, badges, UpdateModeWrite); err != nil || !changed {
+		t.Errorf("write: changed %t, err %v", changed, err)
+	}
+	if changed, err := updateBadges(dir, badges, UpdateModeWrite); err != nil || changed {
+		t.Errorf("rewrite: changed %t, err %v", changed, err)
+	}
+
+	// 不再引用的徽章被删除，其它文件（包括用户自己的同名格式徽章）保留
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("keep"), 0644)
+	os.WriteFile(filepath.Join(dir, "project-stars.svg"), []byte("<svg/>"), 0644)
+	delete(badges, "foo-stars.zh-CN.svg")
+	delete(badges, "foo-issues.zh-CN.svg")
+	delete(badges, "foo-pulls.zh-CN.svg")
+	if changed, err := updateBadges(dir, badges, UpdateModeWrite); err != nil || !changed {
+		t.Errorf("prune: changed %t, err %v", changed, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if want := []string{badgeManifestFile, "README.md", "foo-downloads.zh-CN.svg", "foo-likes.zh-CN.svg", "foo-points.zh-CN.svg", "project-stars.svg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("assets = %v, want %v", got, want)
+	}
+}
+
+func TestParseHealthWeights(t *testing.T) {
+	got, err := parseHealthWeights(" points=40, license=0")
+	if err != nil || !reflect.DeepEqual(got, map[string]int{healthPoints: 40, healthLicense: 0}) {
+		t.Errorf("parseHealthWeights = %v, %v", got, err)
+	}
+	for _, in := range []string{"stars=10", "points", "points=-1", "points=1.5"} {
+		if _, err := parseHealthWeights(in); err == nil {
+			t.Errorf("parseHealthWeights(%q) expected error", in)
+		}
+	}
+}
+
+func TestComputeHealth(t *testing.T) {
+	weights, _ := parseHealthWeights(defaultHealthWeights)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthy := PackageInfo{Code: 1, Name: "foo", Published: "2025-12-01T00:00:00Z", GithubUser: "org", GithubRepo: "foo",
+		GithubOpenIssues: 2, ScoreInfo: PackageScoreInfo{GrantedPoints: 160, MaxPoints: 160, Tags: []string{"license:mit"}}}
+	if got := computeHealth(healthy, weights, now); got.Score != 100 || len(got.Reasons) != 0 {
+		t.Errorf("healthy = %+v", got)
+	}
+
+	// 归档 + 停止维护 + 最新发布超过 2 年 + 评分 80/160 + 30 个 open issues
+	sick := healthy
+	sick.Name = "bar"
+	sick.Published = "2023-01-01T00:00:00Z"
+	sick.Discontinued = true
+	sick.GithubBaseInfo.Archived = true
+	sick.GithubOpenIssues = 30
+	sick.ScoreInfo.GrantedPoints = 80
+	got := computeHealth(sick, weights, now)
+	// 扣分：discontinued 30 + published 20 + archived 20 + points 15 + issues 6 = 91 / 130（扣分相同时按扣分项顺序）
+	if got.Score != 30 {
+		t.Errorf("score = %d, want 30", got.Score)
+	}
+	reasons := formatHealthReasons(got.Reasons, getLocale("en"))
+	if want := []string{"discontinued", "published 1096 days ago", "archived repo", "pub points 80/160", "30 open issues"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons = %v, want %v", reasons, want)
+	}
+
+	// 权重为 0 的扣分项不计入；未关联 GitHub 时不按 issues 与许可证扣分
+	noGithub := PackageInfo{Code: 1, Name: "baz", Published: "2025-12-01T00:00:00Z", Discontinued: true}
+	if got := computeHealth(noGithub, map[string]int{healthDiscontinued: 0, healthPoints: 10}, now); got.Score != 100 || len(got.Reasons) != 0 {
+		t.Errorf("noGithub = %+v", got)
+	}
+	if got := computeHealth(PackageInfo{Code: 0}, weights, now); got.Score != 0 || got.Reasons != nil {
+		t.Errorf("missing package = %+v", got)
+	}
+
+	healthyRow := healthy
+	healthyRow.Health = computeHealth(healthy, weights, now)
+	sick.Health = got
+	options := TableOptions{Health: true, ContributorsLayout: contributorsNone}
+	table := assembleMarkdownTable([]PackageInfo{healthyRow, sick}, options)
+	if !strings.Contains(table, "| <sub>Health</sub> | \n") || !strings.Contains(table, "|:------:| \n") ||
+		!strings.Contains(table, " | 🔴 30 <br/> <sub>discontinued · published 1096 days ago") || !strings.Contains(table, " | 🟢 100 | \n") {
+		t.Errorf("table = %q", table)
+	}
+	if strings.Contains(assembleMarkdownTable([]PackageInfo{sick}, TableOptions{}), "Health") {
+		t.Error("health column shown without options.Health")
+	}
+
+	stats := computePackageStats([]PackageInfo{healthyRow, sick})
+	want := " \n- 🔴 30 [bar](https://pub.dev/packages/bar): 已停止维护, 1096 天未发布, 仓库已归档, pub 评分 80/160, 30 个 open issues \n"
+	if got := renderMarkdownBlock(markerAttention, MarkdownBlocks{Stats: stats, Locale: "zh-CN"}, now); got != want {
+		t.Errorf("attention = %q, want %q", got, want)
+	}
+	for _, threshold := range []int{30, 0} {
+		if got := renderMarkdownBlock(markerAttention, MarkdownBlocks{Stats: stats, Attention: AttentionOptions{Threshold: &threshold}}, now); got != "-" {
+			t.Errorf("attention below threshold %d = %q", threshold, got)
+		}
+	}
+
+	// 自定义权重：单项扣分均未达到 healthMinPenalty，但健康度低于阈值时同样需要关注
+	custom := map[string]int{healthPoints: 50, healthIssues: 50}
+	mild := healthy
+	mild.Name = "mild"
+	mild.ScoreInfo.GrantedPoints = 128 // 比例 0.2
+	mild.GithubOpenIssues = 10         // 比例 0.2
+	mild.Health = computeHealth(mild, custom, now)
+	if mild.Health.Score != 80 || !reflect.DeepEqual(formatHealthReasons(mild.Health.Reasons, getLocale("en")), []string{"low overall score"}) {
+		t.Errorf("mild = %+v", mild.Health)
+	}
+	threshold := 85
+	attention := renderMarkdownBlock(markerAttention, MarkdownBlocks{Stats: computePackageStats([]PackageInfo{mild}), Attention: AttentionOptions{Threshold: &threshold}}, now)
+	if want := " \n- 🟢 80 [mild](https://pub.dev/packages/mild): low overall score \n"; attention != want {
+		t.Errorf("attention = %q, want %q", attention, want)
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	list := []PackageInfo{
+		{Code: 1, Name: "foo", Version: "1.0.0", Advisories: []string{"GHSA-1", "GHSA-2"},
+			GithubBaseInfo: GithubBaseInfo{StargazersCount: 10, Archived: true},
+			ScoreInfo:      PackageScoreInfo{GrantedPoints: 140, MaxPoints: 160, DownloadCount30Days: 400}},
+		{Code: 1, Name: "bar", ScoreInfo: PackageScoreInfo{GrantedPoints: 160, MaxPoints: 160, DownloadCount30Days: 600}},
+		{Code: 1, Name: "new", ScoreInfo: PackageScoreInfo{GrantedPoints: 10, MaxPoints: 160}},
+		{Code: 0, Name: "missing"},
+	}
+	current := newSnapshot(list)
+	if len(current.Packages) != 3 || current.Packages["foo"].Stars != 10 {
+		t.Errorf("newSnapshot = %+v", current)
+	}
+	previous := Snapshot{Packages: map[string]PackageSnapshot{
+		"foo": {Points: 160, MaxPoints: 160, Downloads: 1000, Advisories: []string{"GHSA-1"}},
+		"bar": {Points: 150, MaxPoints: 160, Downloads: 1000},
+		"old": {Points: 160, MaxPoints: 160},
+	}}
+	got := []string{}
+	for _, alert := range compareSnapshots(previous, current, 50) {
+		got = append(got, alert.Package+": "+alert.Message)
+	}
+	want := []string{
+		"foo: pub points dropped from 160 to 140",
+		"foo: new security advisory GHSA-2",
+		"foo: Github repo was archived",
+		"foo: 30-day downloads fell 60% from 1000 to 400",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alerts = %q, want %q", got, want)
+	}
+	if alerts := compareSnapshots(previous, current, 0); len(alerts) != 3 {
+		t.Errorf("alerts without downloads drop = %+v", alerts)
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state", "snapshot.json")
+	if loaded, err := loadSnapshot(filename); loaded != nil || err != nil {
+		t.Errorf("loadSnapshot(missing) = %+v, %v", loaded, err)
+	}
+	if changed, err := writeSnapshot(filename, current, UpdateModeCheck); changed || err != nil {
+		t.Errorf("writeSnapshot(check) = %t, %v", changed, err)
+	}
+	if changed, err := writeSnapshot(filename, current, UpdateModeWrite); !changed || err != nil {
+		t.Errorf("writeSnapshot = %t, %v", changed, err)
+	}
+	if changed, _ := writeSnapshot(filename, current, UpdateModeWrite); changed {
+		t.Error("unchanged snapshot rewritten")
+	}
+	if loaded, err := loadSnapshot(filename); err != nil || !reflect.DeepEqual(*loaded, current) {
+		t.Errorf("loadSnapshot = %+v, %v", loaded, err)
+	}
+}
+
+func TestReportAlerts(t *testing.T) {
+	var attempts atomic.Int32
+	var payload []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 首次失败，验证 POST 沿用重试
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		payload, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	summary := filepath.Join(dir, "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	alerts := []Alert{{Package: "foo", Kind: alertArchived, Message: "Github repo was archived"}}
+	options := AlertOptions{File: filepath.Join(dir, "alerts.json"), Webhook: srv.URL}
+	// check 模式仅打印
+	for _, mode := range []UpdateMode{UpdateModeDryRun, UpdateModeCheck} {
+		if err := reportAlerts(context.Background(), newTestHTTPClient(), alerts, options, mode); err != nil || attempts.Load() != 0 {
+			t.Errorf("reportAlerts(%s) = %v, %d attempts", mode, err, attempts.Load())
+		}
+	}
+	if _, err := os.Stat(options.File); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("alerts file written in check mode")
+	}
+	if _, err := os.Stat(summary); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("job summary written in check mode")
+	}
+	if err := reportAlerts(context.Background(), newTestHTTPClient(), alerts, options, UpdateModeWrite); err != nil {
+		t.Fatalf("reportAlerts: %v", err)
+	}
+	if want := `{"alerts":[{"package":"foo","kind":"archived","message":"Github repo was archived"}]}`; string(payload) != want || attempts.Load() != 2 {
+		t.Errorf("webhook payload = %s after %d attempts, want %s", payload, attempts.Load(), want)
+	}
+	if data, _ := os.ReadFile(options.File); !strings.Contains(string(data), `"kind": "archived"`) {
+		t.Errorf("alerts file = %s", data)
+	}
+	if data, _ := os.ReadFile(summary); !strings.Contains(string(data), "| foo | Github repo was archived |") {
+		t.Errorf("job summary = %s", data)
+	}
+
+	// 无告警：写入空列表，不发送 webhook
+	attempts.Store(0)
+	if err := reportAlerts(context.Background(), newTestHTTPClient(), []Alert{}, options, UpdateModeWrite); err != nil || attempts.Load() != 0 {
+		t.Errorf("reportAlerts(empty) = %v, %d attempts", err, attempts.Load())
+	}
+	if data, _ := os.ReadFile(options.File); string(data) != "[]\n" {
+		t.Errorf("empty alerts file = %q", data)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := reportAlerts(context.Background(), newTestHTTPClient(), alerts, AlertOptions{Webhook: failing.URL}, UpdateModeWrite); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("reportAlerts(400) = %v", err)
+	}
+}
+
+func TestParseNotifySinks(t *testing.T) {
+	got, err := parseNotifySinks("slack=https://hooks.slack.com/services/x\n  teams=https://example.com/teams?a=b ")
+	want := []NotifySink{{Kind: notifySlack, URL: "https://hooks.slack.com/services/x"}, {Kind: notifyTeams, URL: "https://example.com/teams?a=b"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNotifySinks = %+v, %v", got, err)
+	}
+	for _, in := range []string{"https://example.com", "email=https://example.com", "slack=ftp://example.com", "slack="} {
+		if _, err := parseNotifySinks(in); err == nil {
+			t.Errorf("parseNotifySinks(%q) expected error", in)
+		}
+	}
+}
+
+func TestSendNotifications(t *testing.T) {
+	previous := Snapshot{Packages: map[string]PackageSnapshot{"foo": {}, "old": {}}}
+	current := Snapshot{Packages: map[string]PackageSnapshot{"foo": {}, "bar": {}}}
+	added, removed := diffSnapshotPackages(previous, current)
+	if !reflect.DeepEqual(added, []string{"bar"}) || !reflect.DeepEqual(removed, []string{"old"}) {
+		t.Errorf("diffSnapshotPackages = %v, %v", added, removed)
+	}
+	report := RunReport{Repository: "org/repo", Mode: "write", Changed: true, Files: []string{"README.md"}, Packages: 2,
+		Added: added, Removed: removed, Failures: []string{"oops"}}
+	report.Summary = report.summary()
+
+	var mu sync.Mutex
+	payloads := map[string]string{}
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// 首次失败，验证 POST 沿用重试
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		payloads[r.URL.Path] = string(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "notify.json.tmpl")
+	os.WriteFile(templateFile, []byte(`{"summary": {{json .Summary}}, "added": {{json .Added}}, "failed": {{len .Failures}}}`), 0644)
+	tmpl, err := parseNotifyTemplate(templateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NotifyOptions{On: notifyOnChanges, Template: tmpl, Sinks: []NotifySink{
+		{Kind: notifySlack, URL: srv.URL + "/slack"},
+		{Kind: notifyDiscord, URL: srv.URL + "/discord"},
+		{Kind: notifyTeams, URL: srv.URL + "/teams"},
+		{Kind: notifyCustom, URL: srv.URL + "/custom"},
+	}}
+	// dry-run / check 模式不通知
+	for _, mode := range []UpdateMode{UpdateModeDryRun, UpdateModeCheck} {
+		if err := sendNotifications(context.Background(), newTestHTTPClient(), report, options, mode); err != nil || len(payloads) != 0 {
+			t.Errorf("sendNotifications(%s) = %v, sent %v", mode, err, payloads)
+		}
+	}
+	if err := sendNotifications(context.Background(), newTestHTTPClient(), report, options, UpdateModeWrite); err != nil {
+		t.Fatalf("sendNotifications: %v", err)
+	}
+	text := "*pub-dashboard* (org/repo): Updated README.md\nPackages: 2\nAdded: bar\nRemoved: old\nFailures:\n• oops"
+	want := map[string]string{
+		"/slack":   `{"text":` + strconv.Quote(text) + `}`,
+		"/discord": `{"content":` + strconv.Quote(text) + `}`,
+		"/custom":  `{"summary": "Updated README.md", "added": ["bar"], "failed": 1}`,
+	}
+	for path, payload := range want {
+		if payloads[path] != payload {
+			t.Errorf("%s payload = %s, want %s", path, payloads[path], payload)
+		}
+	}
+	if !strings.Contains(payloads["/teams"], `"@type":"MessageCard"`) || !strings.Contains(payloads["/teams"], `Added: bar\n\nRemoved: old`) {
+		t.Errorf("teams payload = %s", payloads["/teams"])
+	}
+
+	// 无变化时不通知（always 除外）
+	quiet := RunReport{Mode: "write", Files: []string{}}
+	quiet.Summary = quiet.summary()
+	if options.shouldNotify(quiet) || !(NotifyOptions{Sinks: options.Sinks, On: notifyOnAlways}).shouldNotify(quiet) {
+		t.Error("shouldNotify without changes")
+	}
+	if quiet.Summary != "No changes" {
+		t.Errorf("summary = %q", quiet.Summary)
+	}
+
+	os.WriteFile(templateFile, []byte(`{"summary": {{.Summary}}}`), 0644)
+	tmpl, _ = parseNotifyTemplate(templateFile)
+	failing := NotifyOptions{Template: tmpl, Sinks: []NotifySink{{Kind: notifyCustom, URL: srv.URL + "/custom"}, {Kind: notifySlack, URL: srv.URL + "/missing?token=secret"}}}
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	err = sendNotifications(context.Background(), newTestHTTPClient(), report, failing, UpdateModeWrite)
+	if err == nil || !strings.Contains(err.Error(), "valid JSON") || !strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("sendNotifications(failing) = %v", err)
+	}
+}